@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/atolix/clyst/request"
+	"github.com/atolix/clyst/spec"
+)
+
+// Field describes one credential input for a security scheme.
+type Field struct {
+	Key      string
+	Label    string
+	Secret   bool
+	Optional bool
+}
+
+const (
+	FieldValue        = "value"
+	FieldUsername     = "username"
+	FieldPassword     = "password"
+	FieldClientID     = "client_id"
+	FieldClientSecret = "client_secret"
+)
+
+// Fields lists the inputs a scheme needs. Unsupported scheme types
+// (e.g. mutualTLS) need none and are skipped when authorizing.
+func Fields(s spec.SecurityScheme) []Field {
+	switch strings.ToLower(s.Type) {
+	case "apikey":
+		return []Field{{Key: FieldValue, Label: "API key", Secret: true}}
+	case "http":
+		if strings.EqualFold(s.Scheme, "basic") {
+			return []Field{
+				{Key: FieldUsername, Label: "Username"},
+				{Key: FieldPassword, Label: "Password", Secret: true},
+			}
+		}
+		return []Field{{Key: FieldValue, Label: "Token", Secret: true}}
+	case "oauth2":
+		switch {
+		case s.Flows.ClientCredentials != nil:
+			return []Field{
+				{Key: FieldClientID, Label: "Client ID"},
+				{Key: FieldClientSecret, Label: "Client secret", Secret: true},
+			}
+		case s.Flows.AuthorizationCode != nil:
+			return []Field{
+				{Key: FieldClientID, Label: "Client ID"},
+				{Key: FieldClientSecret, Label: "Client secret (empty for public clients)", Secret: true, Optional: true},
+			}
+		}
+		return []Field{{Key: FieldValue, Label: "Access token", Secret: true}}
+	case "openidconnect":
+		return []Field{{Key: FieldValue, Label: "Access token", Secret: true}}
+	default:
+		return nil
+	}
+}
+
+func (c Credential) Get(key string) string {
+	switch key {
+	case FieldValue:
+		return c.Value
+	case FieldUsername:
+		return c.Username
+	case FieldPassword:
+		return c.Password
+	case FieldClientID:
+		return c.ClientID
+	case FieldClientSecret:
+		return c.ClientSecret
+	}
+	return ""
+}
+
+func (c Credential) sameFields(other Credential) bool {
+	for _, key := range []string{FieldValue, FieldUsername, FieldPassword, FieldClientID, FieldClientSecret} {
+		if c.Get(key) != other.Get(key) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Credential) Set(key, value string) {
+	switch key {
+	case FieldValue:
+		c.Value = value
+	case FieldUsername:
+		c.Username = value
+	case FieldPassword:
+		c.Password = value
+	case FieldClientID:
+		c.ClientID = value
+	case FieldClientSecret:
+		c.ClientSecret = value
+	}
+}
+
+// Scheme pairs a security scheme with its name in the spec.
+type Scheme struct {
+	Name   string
+	Scheme spec.SecurityScheme
+	Scopes []string
+}
+
+// Authorizer applies the effective security requirement of an operation
+// using credentials from a Store. It implements request.Authorizer and
+// request.Refresher; OAuth2 tokens are cached in the store with their expiry.
+type Authorizer struct {
+	// Tokens performs OAuth2 grants; replace it to point at a stub provider.
+	Tokens *TokenManager
+
+	specID  string
+	schemes map[string]spec.SecurityScheme
+	store   *Store
+}
+
+func NewAuthorizer(doc *spec.OpenApiSpec, store *Store) *Authorizer {
+	return &Authorizer{
+		Tokens:  NewTokenManager(),
+		specID:  doc.ID(),
+		schemes: doc.SecuritySchemes,
+		store:   store,
+	}
+}
+
+// Missing returns the schemes the user still has to provide credentials
+// for, or nil when some requirement of the operation is already satisfied.
+func (a *Authorizer) Missing(ep request.Endpoint) []Scheme {
+	if _, ok := a.satisfied(ep); ok || len(ep.Operation.Security) == 0 {
+		return nil
+	}
+
+	var out []Scheme
+	for _, s := range a.requirement(ep.Operation.Security[0]) {
+		if !a.complete(s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Schemes lists the schemes of every requirement of the operation, so
+// their credentials can be entered or changed.
+func (a *Authorizer) Schemes(ep request.Endpoint) []Scheme {
+	var out []Scheme
+	seen := map[string]bool{}
+	for _, req := range ep.Operation.Security {
+		for _, s := range a.requirement(req) {
+			if !seen[s.Name] {
+				seen[s.Name] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// Credential returns what is stored for the named scheme of the current
+// spec.
+func (a *Authorizer) Credential(scheme string) Credential {
+	c, _ := a.store.Get(a.specID, scheme)
+	return c
+}
+
+// Save stores a credential for the named scheme of the current spec. A
+// cached OAuth2 token is kept while the fields it was granted for stay the
+// same.
+func (a *Authorizer) Save(scheme string, c Credential) error {
+	if old, ok := a.store.Get(a.specID, scheme); ok && c.Token == nil && old.sameFields(c) {
+		c.Token = old.Token
+	}
+	return a.store.Set(a.specID, scheme, c)
+}
+
+func (a *Authorizer) Authorize(req *http.Request, ep request.Endpoint) error {
+	schemes, ok := a.satisfied(ep)
+	if !ok {
+		return nil
+	}
+	for _, s := range schemes {
+		if err := a.apply(req, s); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// satisfied picks the first requirement whose schemes all have credentials.
+func (a *Authorizer) satisfied(ep request.Endpoint) ([]Scheme, bool) {
+	for _, req := range ep.Operation.Security {
+		schemes := a.requirement(req)
+		ok := true
+		for _, s := range schemes {
+			if !a.complete(s) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return schemes, true
+		}
+	}
+	return nil, false
+}
+
+func (a *Authorizer) requirement(req spec.SecurityRequirement) []Scheme {
+	names := make([]string, 0, len(req))
+	for name := range req {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]Scheme, 0, len(names))
+	for _, name := range names {
+		if s, ok := a.schemes[name]; ok {
+			out = append(out, Scheme{Name: name, Scheme: s, Scopes: req[name]})
+		}
+	}
+	return out
+}
+
+func (a *Authorizer) complete(s Scheme) bool {
+	cred, _ := a.store.Get(a.specID, s.Name)
+	for _, f := range Fields(s.Scheme) {
+		if !f.Optional && strings.TrimSpace(cred.Get(f.Key)) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Authorizer) apply(req *http.Request, s Scheme) error {
+	cred, _ := a.store.Get(a.specID, s.Name)
+	scheme := s.Scheme
+
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		switch strings.ToLower(scheme.In) {
+		case "header":
+			req.Header.Set(scheme.Name, cred.Value)
+		case "query":
+			q := req.URL.Query()
+			q.Set(scheme.Name, cred.Value)
+			req.URL.RawQuery = q.Encode()
+		case "cookie":
+			req.AddCookie(&http.Cookie{Name: scheme.Name, Value: cred.Value})
+		default:
+			return fmt.Errorf("unsupported apiKey location %q", scheme.In)
+		}
+	case "http":
+		switch strings.ToLower(scheme.Scheme) {
+		case "basic":
+			req.SetBasicAuth(cred.Username, cred.Password)
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+cred.Value)
+		default:
+			req.Header.Set("Authorization", scheme.Scheme+" "+cred.Value)
+		}
+	case "oauth2", "openidconnect":
+		token := cred.Value
+		if hasGrant(scheme) {
+			tok, err := a.oauthToken(req.Context(), s, cred)
+			if err != nil {
+				return err
+			}
+			token = tok.AccessToken
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// Refresh is called after a 401. It renews the OAuth2 tokens used for the
+// endpoint and reports whether retrying the request can help.
+func (a *Authorizer) Refresh(ctx context.Context, ep request.Endpoint) (bool, error) {
+	schemes, ok := a.satisfied(ep)
+	if !ok {
+		return false, nil
+	}
+
+	refreshed := false
+	for _, s := range schemes {
+		if !hasGrant(s.Scheme) {
+			continue
+		}
+		cred, _ := a.store.Get(a.specID, s.Name)
+		if cred.Token == nil {
+			continue
+		}
+		// Drop the rejected access token; oauthToken then refreshes or
+		// runs the grant again.
+		cred.Token.AccessToken = ""
+		if _, err := a.oauthToken(ctx, s, cred); err != nil {
+			return false, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		refreshed = true
+	}
+	return refreshed, nil
+}
+
+func hasGrant(s spec.SecurityScheme) bool {
+	return s.Flows.ClientCredentials != nil || s.Flows.AuthorizationCode != nil
+}
+
+// oauthToken returns a usable token for the scheme: the cached one while it
+// is valid, a refreshed one when a refresh token exists, or a new grant.
+func (a *Authorizer) oauthToken(ctx context.Context, s Scheme, cred Credential) (*Token, error) {
+	if cred.Token.Valid() {
+		return cred.Token, nil
+	}
+
+	flows := s.Scheme.Flows
+	cfg := OAuthConfig{ClientID: cred.ClientID, ClientSecret: cred.ClientSecret, Scopes: s.Scopes}
+
+	var (
+		tok *Token
+		err error
+	)
+	switch {
+	case flows.ClientCredentials != nil:
+		cfg.TokenURL = flows.ClientCredentials.TokenURL
+		if cred.Token != nil && cred.Token.RefreshToken != "" {
+			tok, err = a.Tokens.Refresh(ctx, cfg, cred.Token.RefreshToken)
+		}
+		if tok == nil {
+			tok, err = a.Tokens.ClientCredentials(ctx, cfg)
+		}
+	case flows.AuthorizationCode != nil:
+		cfg.AuthURL = flows.AuthorizationCode.AuthorizationURL
+		cfg.TokenURL = flows.AuthorizationCode.TokenURL
+		if cred.Token != nil && cred.Token.RefreshToken != "" {
+			tok, err = a.Tokens.Refresh(ctx, cfg, cred.Token.RefreshToken)
+		}
+		if tok == nil {
+			tok, err = a.Tokens.AuthorizationCode(ctx, cfg)
+		}
+	default:
+		return nil, errors.New("no supported OAuth2 flow")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred.Token = tok
+	if err := a.store.Set(a.specID, s.Name, cred); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
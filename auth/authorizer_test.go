@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveKeepsTokenOfSameClient(t *testing.T) {
+	a, ep := newOAuthAuthorizer(t, nil, "http://idp.example/token")
+	if got := a.Schemes(ep); len(got) != 1 || got[0].Name != "oauth" {
+		t.Fatalf("Schemes() = %+v, want the oauth scheme", got)
+	}
+
+	cred := a.Credential("oauth")
+	cred.Token = &Token{AccessToken: "cached", Expiry: time.Now().Add(time.Hour)}
+	if err := a.store.Set(a.specID, "oauth", cred); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Save("oauth", Credential{ClientID: "app", ClientSecret: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	if tok := a.Credential("oauth").Token; tok == nil || tok.AccessToken != "cached" {
+		t.Errorf("token after saving the same client = %+v, want it kept", tok)
+	}
+
+	if err := a.Save("oauth", Credential{ClientID: "other", ClientSecret: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	if tok := a.Credential("oauth").Token; tok != nil {
+		t.Errorf("token after changing the client = %+v, want it dropped", tok)
+	}
+}
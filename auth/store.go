@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const credentialsFilename = "credentials.json"
+
+// Credential holds the secret material for one security scheme. Which
+// fields are used depends on the scheme type; see Fields.
+type Credential struct {
+	Value        string `json:"value,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Token caches the last OAuth2 token obtained with this credential.
+	Token *Token `json:"token,omitempty"`
+}
+
+// Store keeps credentials per spec and scheme name in the user's config
+// directory, away from the preset file that teams commit.
+type Store struct {
+	path string
+	// mu guards data, which a send updates with tokens while the screens
+	// read it.
+	mu   sync.Mutex
+	data map[string]map[string]Credential
+}
+
+// DefaultStorePath returns <user config dir>/clyst/credentials.json.
+func DefaultStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "clyst", credentialsFilename), nil
+}
+
+// LoadStore reads the credential file at path. An empty path gives an
+// in-memory store that is not persisted.
+func LoadStore(path string) (*Store, error) {
+	data := map[string]map[string]Credential{}
+	if path == "" {
+		return &Store{data: data}, nil
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &data); err != nil {
+				return nil, err
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return &Store{path: path, data: data}, nil
+}
+
+func (s *Store) Get(specID, scheme string) (Credential, bool) {
+	if s == nil {
+		return Credential{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.data[specID][scheme]
+	return c, ok
+}
+
+func (s *Store) Set(specID, scheme string, c Credential) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data[specID] == nil {
+		s.data[specID] = map[string]Credential{}
+	}
+	s.data[specID][scheme] = c
+	return s.persist()
+}
+
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, payload, 0o600)
+}
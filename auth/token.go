@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// expiryLeeway treats tokens as expired slightly early so a request does
+// not race the server-side expiry.
+const expiryLeeway = 30 * time.Second
+
+// Token is an OAuth2 token response, cached in the credential store.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token can still be used.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(expiryLeeway).Before(t.Expiry)
+}
+
+// OAuthConfig is what a token request needs from the spec and the stored credential.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	Scopes       []string
+}
+
+// TokenManager obtains OAuth2 tokens with the client-credentials and
+// authorization-code (PKCE) grants and refreshes them.
+type TokenManager struct {
+	Client *http.Client
+	// OpenBrowser shows the authorization URL to the user. Defaults to the
+	// platform's URL opener; tests can follow the URL themselves.
+	OpenBrowser func(authURL string) error
+	// Timeout bounds how long the loopback listener waits for the redirect.
+	Timeout time.Duration
+}
+
+func NewTokenManager() *TokenManager {
+	return &TokenManager{Client: http.DefaultClient, OpenBrowser: openBrowser, Timeout: 5 * time.Minute}
+}
+
+func (m *TokenManager) ClientCredentials(ctx context.Context, cfg OAuthConfig) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	return m.exchange(ctx, cfg, form)
+}
+
+func (m *TokenManager) Refresh(ctx context.Context, cfg OAuthConfig, refreshToken string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	tok, err := m.exchange(ctx, cfg, form)
+	if err != nil {
+		return nil, err
+	}
+	// Providers may omit the refresh token when it does not rotate.
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = refreshToken
+	}
+	return tok, nil
+}
+
+type promptKey struct{}
+
+// WithPrompt returns a context that makes AuthorizationCode show the
+// authorization URL with show instead of printing it on stderr, for
+// callers that own the terminal.
+func WithPrompt(ctx context.Context, show func(authURL string)) context.Context {
+	return context.WithValue(ctx, promptKey{}, show)
+}
+
+// AuthorizationCode runs the authorization-code grant with PKCE. It listens
+// on a loopback port for the redirect, opens the authorization URL and
+// exchanges the returned code. Canceling ctx stops the wait for the
+// redirect.
+func (m *TokenManager) AuthorizationCode(ctx context.Context, cfg OAuthConfig) (*Token, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	redirectURI := fmt.Sprintf("http://%s/callback", ln.Addr().String())
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	authURL, err := url.Parse(cfg.AuthURL)
+	if err != nil {
+		return nil, err
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	if len(cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	authURL.RawQuery = q.Encode()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	results := make(chan callback, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var res callback
+		switch {
+		case q.Get("state") != state:
+			res.err = errors.New("state mismatch in authorization response")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			res.err = errors.New("authorization response has no code")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "clyst: authorization complete, you can close this tab.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	if show, ok := ctx.Value(promptKey{}).(func(string)); ok {
+		show(authURL.String())
+	} else {
+		fmt.Fprintln(os.Stderr, "Open this URL to authorize clyst:\n ", authURL.String())
+	}
+	if m.OpenBrowser != nil {
+		_ = m.OpenBrowser(authURL.String())
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	var res callback
+	select {
+	case res = <-results:
+	case <-time.After(timeout):
+		return nil, errors.New("timed out waiting for the authorization redirect")
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for the authorization redirect: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	return m.exchange(ctx, cfg, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+// exchange posts a token request, canceled with ctx. Confidential clients
+// authenticate with HTTP basic; public clients send their client_id in the
+// form.
+func (m *TokenManager) exchange(ctx context.Context, cfg OAuthConfig, form url.Values) (*Token, error) {
+	if cfg.ClientSecret == "" {
+		form.Set("client_id", cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode < 300 {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 || body.Error != "" {
+		msg := res.Status
+		if body.Error != "" {
+			msg = strings.TrimSpace(body.Error + " " + body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token endpoint: %s", msg)
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("token endpoint returned no access_token")
+	}
+
+	tok := &Token{AccessToken: body.AccessToken, TokenType: body.TokenType, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return errors.New("no display to open a browser")
+		}
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
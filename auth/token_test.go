@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atolix/clyst/request"
+	"github.com/atolix/clyst/spec"
+)
+
+// stubProvider is a token endpoint that issues numbered access tokens and
+// records the grants it was asked for.
+type stubProvider struct {
+	t         *testing.T
+	expiresIn int64
+
+	mu        sync.Mutex
+	grants    []string
+	issued    int
+	challenge string
+}
+
+func newStubProvider(t *testing.T) (*stubProvider, *httptest.Server) {
+	p := &stubProvider{t: t, expiresIn: 3600}
+	srv := httptest.NewServer(http.HandlerFunc(p.serveToken))
+	t.Cleanup(srv.Close)
+	return p, srv
+}
+
+func (p *stubProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		p.t.Errorf("parse token request: %v", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	grant := r.PostForm.Get("grant_type")
+	p.grants = append(p.grants, grant)
+	w.Header().Set("Content-Type", "application/json")
+
+	switch grant {
+	case "client_credentials":
+		if id, secret, ok := r.BasicAuth(); !ok || id != "app" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad client"}`)
+			return
+		}
+	case "authorization_code":
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "the-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		if r.PostForm.Get("client_id") != "public-app" {
+			p.t.Errorf("client_id = %q, want the public client's id", r.PostForm.Get("client_id"))
+		}
+	case "refresh_token":
+		if !strings.HasPrefix(r.PostForm.Get("refresh_token"), "refresh-") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+	}
+
+	p.issued++
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  fmt.Sprintf("token-%d", p.issued),
+		"token_type":    "Bearer",
+		"refresh_token": fmt.Sprintf("refresh-%d", p.issued),
+		"expires_in":    p.expiresIn,
+	})
+}
+
+func (p *stubProvider) grantLog() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.grants...)
+}
+
+// followRedirect stands in for the browser: it approves the authorization
+// request by calling the loopback redirect with a code.
+func (p *stubProvider) followRedirect(authURL string) error {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	p.mu.Lock()
+	p.challenge = q.Get("code_challenge")
+	p.mu.Unlock()
+	if q.Get("code_challenge_method") != "S256" {
+		return errors.New("PKCE challenge method is not S256")
+	}
+	go func() {
+		res, err := http.Get(q.Get("redirect_uri") + "?code=the-code&state=" + url.QueryEscape(q.Get("state")))
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	return nil
+}
+
+func TestClientCredentials(t *testing.T) {
+	_, srv := newStubProvider(t)
+	m := &TokenManager{Client: srv.Client()}
+
+	tok, err := m.ClientCredentials(context.Background(), OAuthConfig{ClientID: "app", ClientSecret: "s3cret", TokenURL: srv.URL, Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatalf("ClientCredentials() error = %v", err)
+	}
+	if tok.AccessToken != "token-1" || tok.RefreshToken != "refresh-1" || !tok.Valid() {
+		t.Errorf("ClientCredentials() = %+v, want a valid token-1", tok)
+	}
+
+	_, err = m.ClientCredentials(context.Background(), OAuthConfig{ClientID: "app", ClientSecret: "wrong", TokenURL: srv.URL})
+	if err == nil || !strings.Contains(err.Error(), "invalid_client bad client") {
+		t.Errorf("ClientCredentials() with a wrong secret error = %v, want invalid_client", err)
+	}
+}
+
+func TestAuthorizationCodePKCE(t *testing.T) {
+	p, srv := newStubProvider(t)
+	m := &TokenManager{Client: srv.Client(), OpenBrowser: p.followRedirect, Timeout: 5 * time.Second}
+
+	var shown string
+	ctx := WithPrompt(context.Background(), func(u string) { shown = u })
+	tok, err := m.AuthorizationCode(ctx, OAuthConfig{ClientID: "public-app", AuthURL: "https://idp.example/authorize", TokenURL: srv.URL})
+	if err != nil {
+		t.Fatalf("AuthorizationCode() error = %v", err)
+	}
+	if tok.AccessToken != "token-1" {
+		t.Errorf("AuthorizationCode() token = %q, want token-1", tok.AccessToken)
+	}
+	if !strings.HasPrefix(shown, "https://idp.example/authorize?") {
+		t.Errorf("prompt got %q, want the authorization URL", shown)
+	}
+}
+
+func TestAuthorizationCodeCanceled(t *testing.T) {
+	_, srv := newStubProvider(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &TokenManager{
+		Client:      srv.Client(),
+		OpenBrowser: func(string) error { cancel(); return nil },
+		Timeout:     5 * time.Second,
+	}
+
+	_, err := m.AuthorizationCode(WithPrompt(ctx, func(string) {}), OAuthConfig{ClientID: "public-app", AuthURL: "https://idp.example/authorize", TokenURL: srv.URL})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("AuthorizationCode() error = %v, want context.Canceled", err)
+	}
+}
+
+// newOAuthAuthorizer returns an authorizer for a spec with one
+// client-credentials scheme named "oauth" whose token URL is tokenURL.
+func newOAuthAuthorizer(t *testing.T, client *http.Client, tokenURL string) (*Authorizer, request.Endpoint) {
+	t.Helper()
+	doc := &spec.OpenApiSpec{
+		Info: spec.Info{Title: "stub", Version: "1"},
+		SecuritySchemes: map[string]spec.SecurityScheme{
+			"oauth": {Type: "oauth2", Flows: spec.OAuthFlows{ClientCredentials: &spec.OAuthFlow{TokenURL: tokenURL}}},
+		},
+	}
+	store, err := LoadStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuthorizer(doc, store)
+	a.Tokens.Client = client
+	if err := a.Save("oauth", Credential{ClientID: "app", ClientSecret: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	ep := request.Endpoint{Method: "GET", Path: "/things", Operation: spec.Operation{
+		Security: []spec.SecurityRequirement{{"oauth": nil}},
+	}}
+	return a, ep
+}
+
+func authorizationOf(t *testing.T, a *Authorizer, ep request.Endpoint) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "http://api.example/things", nil)
+	if err := a.Authorize(req, ep); err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestAuthorizerCachesTokenUntilExpiry(t *testing.T) {
+	p, srv := newStubProvider(t)
+	a, ep := newOAuthAuthorizer(t, srv.Client(), srv.URL)
+
+	if got := authorizationOf(t, a, ep); got != "Bearer token-1" {
+		t.Fatalf("first Authorization = %q, want Bearer token-1", got)
+	}
+	if got := authorizationOf(t, a, ep); got != "Bearer token-1" {
+		t.Fatalf("second Authorization = %q, want the cached token-1", got)
+	}
+	if got := p.grantLog(); len(got) != 1 {
+		t.Fatalf("grants = %v, want a single one", got)
+	}
+
+	// Within the expiry leeway the token counts as expired and is
+	// refreshed with its refresh token.
+	cred, _ := a.store.Get(a.specID, "oauth")
+	cred.Token.Expiry = time.Now().Add(expiryLeeway / 2)
+	if err := a.store.Set(a.specID, "oauth", cred); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorizationOf(t, a, ep); got != "Bearer token-2" {
+		t.Fatalf("Authorization after expiry = %q, want Bearer token-2", got)
+	}
+	if got := p.grantLog(); strings.Join(got, ",") != "client_credentials,refresh_token" {
+		t.Errorf("grants = %v, want client_credentials then refresh_token", got)
+	}
+}
+
+func TestSenderRetriesAfter401(t *testing.T) {
+	p, tokenSrv := newStubProvider(t)
+	var seen []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer api.Close()
+
+	a, ep := newOAuthAuthorizer(t, tokenSrv.Client(), tokenSrv.URL)
+	input, _, err := request.AssembleInput(spec.Server{URL: api.URL}, ep, request.StaticInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := request.Sender{Auth: a}.Send(ep, input)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if result.Response.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200 after the retry", result.Response.StatusCode)
+	}
+	if strings.Join(seen, ",") != "Bearer token-1,Bearer token-2" {
+		t.Errorf("API saw %v, want token-1 then the refreshed token-2", seen)
+	}
+	if got := p.grantLog(); strings.Join(got, ",") != "client_credentials,refresh_token" {
+		t.Errorf("grants = %v, want client_credentials then refresh_token", got)
+	}
+}
+
+func TestAuthorizerRefreshCanceled(t *testing.T) {
+	p, srv := newStubProvider(t)
+	a, ep := newOAuthAuthorizer(t, srv.Client(), srv.URL)
+	authorizationOf(t, a, ep)
+
+	// The token endpoint hangs on the refresh until the caller gives up.
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Read the form so the server notices when the client hangs up.
+		_ = r.ParseForm()
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer hang.Close()
+	defer close(release)
+	a.schemes["oauth"].Flows.ClientCredentials.TokenURL = hang.URL
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Refresh(ctx, ep)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Refresh() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Refresh() did not return after its context was canceled")
+	}
+	if got := p.grantLog(); len(got) != 1 {
+		t.Errorf("grants = %v, want only the first client_credentials", got)
+	}
+}
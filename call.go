@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/atolix/clyst/config"
+	"github.com/atolix/clyst/output"
+	"github.com/atolix/clyst/params"
+	"github.com/atolix/clyst/request"
+	"github.com/atolix/clyst/spec"
+)
+
+// Exit codes of `clyst call`.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitClientError = 4
+	exitServerError = 5
+)
+
+const callUsage = `Usage: clyst call [flags] METHOD PATH
+
+Sends one request without the TUI. PATH is the path as written in the spec
+(/users/{id}) or a concrete path (/users/3).
+
+Flags:
+`
+
+const runUsage = `Usage: clyst run [flags] "METHOD PATH" --preset NAME
+
+Sends a saved preset without the TUI. Other flags override the preset's values.
+
+Flags:
+`
+
+// kvFlag collects repeated name=value flags.
+type kvFlag map[string]string
+
+func (f kvFlag) String() string { return "" }
+
+func (f kvFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	f[strings.TrimSpace(name)] = value
+	return nil
+}
+
+// headerFlag also accepts the "Name: value" form.
+type headerFlag struct{ kvFlag }
+
+func (f headerFlag) Set(s string) error {
+	if name, value, ok := strings.Cut(s, ":"); ok && !strings.Contains(name, "=") {
+		return f.kvFlag.Set(name + "=" + strings.TrimSpace(value))
+	}
+	return f.kvFlag.Set(s)
+}
+
+type callOptions struct {
+	spec       string
+	env        string
+	server     string
+	serverVars kvFlag
+	path       kvFlag
+	query      kvFlag
+	cookie     kvFlag
+	headers    kvFlag
+	body       string
+	output     string
+	preset     string
+}
+
+// runCall implements `clyst call` and, with a required --preset,
+// `clyst run`.
+func runCall(name string, args []string) int {
+	opts := callOptions{
+		serverVars: kvFlag{},
+		path:       kvFlag{},
+		query:      kvFlag{},
+		cookie:     kvFlag{},
+		headers:    kvFlag{},
+	}
+	usage := callUsage
+	if name == "run" {
+		usage = runUsage
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.spec, "spec", "", "spec file, http(s) URL, or - to read from stdin")
+	fs.StringVar(&opts.env, "env", "", "environment from the config (default: the first one)")
+	fs.StringVar(&opts.server, "server", "", "server URL, overriding the spec and environment")
+	fs.Var(opts.serverVars, "server-var", "server variable `name=value` (repeatable)")
+	fs.Var(opts.path, "path", "path parameter `name=value` (repeatable)")
+	fs.Var(opts.query, "query", "query parameter `name=value` (repeatable)")
+	fs.Var(headerFlag{opts.headers}, "header", "request header `name=value` or 'Name: value' (repeatable)")
+	fs.Var(opts.cookie, "cookie", "cookie parameter `name=value` (repeatable)")
+	fs.StringVar(&opts.body, "body", "", "request body, @file to read a file or @- for stdin")
+	fs.StringVar(&opts.output, "output", string(output.ModePretty), "response output: pretty|json|raw|body|headers|status")
+	fs.StringVar(&opts.preset, "preset", "", "saved preset `name` to start from")
+
+	// Allow flags before and after METHOD and PATH.
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	// "GET /users/{id}" may be passed as one argument.
+	if len(positional) == 1 {
+		positional = strings.Fields(positional[0])
+	}
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, name+": expected METHOD and PATH")
+		fs.Usage()
+		return exitUsage
+	}
+	if name == "run" && opts.preset == "" {
+		fmt.Fprintln(os.Stderr, "run: --preset is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	code, err := call(strings.ToLower(positional[0]), positional[1], opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, name+":", err)
+	}
+	return code
+}
+
+func call(method, path string, opts callOptions) (int, error) {
+	mode, err := output.ParseMode(opts.output)
+	if err != nil {
+		return exitUsage, err
+	}
+
+	if opts.spec == "-" && opts.body == "@-" {
+		return exitUsage, errors.New("--spec - and --body @- both read stdin; pass one of them as a file")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return exitError, fmt.Errorf("config: %w", err)
+	}
+
+	var env *config.Environment
+	if opts.env != "" {
+		if env = cfg.Environment(opts.env); env == nil {
+			return exitUsage, fmt.Errorf("unknown environment %q (have: %s)", opts.env, strings.Join(cfg.EnvironmentNames(), ", "))
+		}
+	} else if names := cfg.EnvironmentNames(); len(names) > 0 {
+		env = cfg.Environment(names[0])
+	}
+
+	source := opts.spec
+	if source == "" {
+		if source, err = discoverSingleSpec(cfg); err != nil {
+			return exitUsage, err
+		}
+	}
+	doc, err := spec.LoadSource(source, specLoadOptions(cfg))
+	if err != nil {
+		return exitError, fmt.Errorf("load %s: %w", source, err)
+	}
+	if doc.Stale {
+		fmt.Fprintf(os.Stderr, "Could not reach %s; using the cached copy.\n", source)
+	}
+
+	template, op, pathVars, ok := doc.FindOperation(method, path)
+	if !ok {
+		return exitUsage, fmt.Errorf("no operation %s %s in %s", strings.ToUpper(method), path, source)
+	}
+	ep := request.Endpoint{Method: method, Path: template, Operation: op}
+
+	var preset params.StoredParams
+	if opts.preset != "" {
+		store, err := params.Load(".", doc.ID())
+		if err != nil {
+			return exitError, fmt.Errorf("read presets: %w", err)
+		}
+		var ok bool
+		if preset, ok = store.PresetNamed(method, template, opts.preset); !ok {
+			return exitUsage, fmt.Errorf("no preset %q for %s %s", opts.preset, strings.ToUpper(method), template)
+		}
+	}
+	input := presetInput(preset, opts)
+
+	for name, v := range pathVars {
+		if _, set := opts.path[name]; !set {
+			input.Path[name] = v
+		}
+	}
+	for _, p := range op.Parameters {
+		if p.In == "path" && input.Path[p.Name] == "" {
+			return exitUsage, fmt.Errorf("missing path parameter %q (use --path %s=...)", p.Name, p.Name)
+		}
+	}
+
+	server, err := callServer(ep, env, opts.server)
+	if err != nil {
+		return exitUsage, err
+	}
+
+	if opts.body != "" {
+		if input.Body, err = readBody(opts.body); err != nil {
+			return exitUsage, err
+		}
+	}
+
+	var provider request.InputProvider = input
+	if env != nil {
+		provider = request.EnvProvider{InputProvider: provider, Variables: env.Variables, Headers: env.Headers, Parameters: op.Parameters}
+	}
+	// Values that do not fit the spec are still sent, so a server can be
+	// probed with them; the problems are only reported.
+	for _, e := range request.Validate(server, ep, provider) {
+		fmt.Fprintln(os.Stderr, "warning:", e)
+	}
+	assembled, _, err := request.AssembleInput(server, ep, provider)
+	if err != nil {
+		return exitError, err
+	}
+
+	authz, err := loadAuthorizer(doc)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: failed to read credentials:", err)
+	}
+	if missing := authz.Missing(ep); len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for _, s := range missing {
+			names = append(names, s.Name)
+		}
+		return exitError, fmt.Errorf("no stored credentials for %s; run clyst interactively once to enter them", strings.Join(names, ", "))
+	}
+
+	envName := ""
+	if env != nil {
+		envName = env.Name
+	}
+	// The history leaves the secret fields out: as the preset marks them,
+	// or by name as the form would.
+	values := request.PresetValues(ep, input)
+	values.Secrets = preset.Secrets
+	if opts.preset == "" {
+		values.Secrets = values.SecretsByName()
+	}
+	result, warning, err := sendRecorded(context.Background(), doc, envName, ep, authz, serverBase(server, provider), assembled, values)
+	if err != nil {
+		return exitError, err
+	}
+	if warning != nil {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	if err := output.Write(os.Stdout, result, mode, output.ColorEnabled(os.Stdout)); err != nil {
+		return exitError, err
+	}
+	warnIssues(result, mode)
+	return statusExitCode(result.Response.StatusCode), nil
+}
+
+// presetInput starts from the preset's values and lays the flags over them.
+func presetInput(preset params.StoredParams, opts callOptions) request.StaticInput {
+	input := request.PresetInput(preset)
+	input.ServerVariables = opts.serverVars
+	input.Path = overlay(input.Path, opts.path)
+	input.Query = overlay(input.Query, opts.query)
+	input.Cookie = overlay(input.Cookie, opts.cookie)
+	input.Headers = overlay(input.Headers, opts.headers)
+	return input
+}
+
+func overlay(base, over map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(over))
+	maps.Copy(out, base)
+	maps.Copy(out, over)
+	return out
+}
+
+// discoverSingleSpec finds the spec the way the TUI does, but cannot ask
+// which one to use when there are several.
+func discoverSingleSpec(cfg *config.Config) (string, error) {
+	names, err := config.DefineSpecNames(cfg)
+	if err != nil {
+		return "", err
+	}
+	found, err := spec.DiscoverSpecFiles(".", names)
+	if err != nil {
+		return "", err
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no spec file found (looked for: %s); pass --spec", strings.Join(names, ", "))
+	case 1:
+		return found[0], nil
+	default:
+		sort.Strings(found)
+		return "", fmt.Errorf("several spec files found (%s); pass --spec", strings.Join(found, ", "))
+	}
+}
+
+// callServer picks --server, then the environment's base URL, then the
+// first server of the operation.
+func callServer(ep request.Endpoint, env *config.Environment, override string) (spec.Server, error) {
+	switch {
+	case override != "":
+		return spec.Server{URL: override}, nil
+	case env != nil && env.BaseURL != "":
+		return spec.Server{URL: request.Interpolate(env.BaseURL, env.Variables)}, nil
+	case len(ep.Operation.Servers) > 0:
+		return ep.Operation.Servers[0], nil
+	}
+	return spec.Server{}, errors.New("no server URL (define `servers` in the spec or pass --server)")
+}
+
+func readBody(arg string) (string, error) {
+	switch {
+	case arg == "@-":
+		b, err := io.ReadAll(os.Stdin)
+		return string(b), err
+	case strings.HasPrefix(arg, "@"):
+		b, err := os.ReadFile(arg[1:])
+		return string(b), err
+	}
+	return arg, nil
+}
+
+// statusExitCode maps 2xx and 3xx to success and 4xx/5xx to distinct codes
+// so scripts can tell client and server errors apart.
+func statusExitCode(status int) int {
+	switch {
+	case status >= 500:
+		return exitServerError
+	case status >= 400:
+		return exitClientError
+	}
+	return exitOK
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -9,7 +10,45 @@ import (
 )
 
 type Config struct {
-	SpecFiles []string `yaml:"spec_files"`
+	SpecFiles       []string `yaml:"spec_files"`
+	AllowRemoteRefs bool     `yaml:"allow_remote_refs"`
+	// Environments are switchable targets such as dev, staging and prod.
+	// The first one is active until another is picked.
+	Environments []Environment `yaml:"environments"`
+}
+
+// Environment overrides the spec's servers and supplies headers and
+// {{variables}} for request values.
+type Environment struct {
+	Name      string            `yaml:"name"`
+	BaseURL   string            `yaml:"base_url"`
+	Headers   map[string]string `yaml:"headers"`
+	Variables map[string]string `yaml:"variables"`
+}
+
+// EnvironmentNames lists the configured environments in file order.
+func (c *Config) EnvironmentNames() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Environments))
+	for _, e := range c.Environments {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Environment returns the environment called name, or nil.
+func (c *Config) Environment(name string) *Environment {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Environments {
+		if c.Environments[i].Name == name {
+			return &c.Environments[i]
+		}
+	}
+	return nil
 }
 
 var DefaultSpecNames = []string{
@@ -17,6 +56,10 @@ var DefaultSpecNames = []string{
 	"spec.yml",
 	"openapi.yml",
 	"openapi.yaml",
+	"openapi.json",
+	"swagger.yml",
+	"swagger.yaml",
+	"swagger.json",
 }
 
 var DefaultCandicates = []string{
@@ -53,6 +96,17 @@ func Load() (*Config, error) {
 		cfg.SpecFiles[i] = filepath.Clean(p)
 	}
 
+	seen := map[string]bool{}
+	for _, e := range cfg.Environments {
+		if e.Name == "" {
+			return nil, errors.New("config.environments contains an entry without a name")
+		}
+		if seen[e.Name] {
+			return nil, fmt.Errorf("config.environments has a duplicate name %q", e.Name)
+		}
+		seen[e.Name] = true
+	}
+
 	return &cfg, nil
 }
 
@@ -0,0 +1,321 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atolix/clyst/params"
+	"github.com/atolix/clyst/request"
+)
+
+const (
+	historyFilename = "history.jsonl"
+	// maxFileBytes is the size at which the file is rotated. The previous
+	// file is kept as history.jsonl.1, so the history holds between one
+	// and two files' worth of requests.
+	maxFileBytes = 8 << 20
+	// maxBodyBytes caps each stored request and response body.
+	maxBodyBytes = 256 << 10
+)
+
+// Entry is one sent request with its response.
+type Entry struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	SpecID      string    `json:"spec_id"`
+	Environment string    `json:"environment,omitempty"`
+	// Method and Path identify the endpoint; Path is the spec's template.
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Server is the base URL the request went to.
+	Server string `json:"server"`
+	// Input holds the values as entered, before environment variables were
+	// filled in, so an entry can be sent again or saved as a preset.
+	Input    params.StoredParams `json:"input"`
+	Request  Request             `json:"request"`
+	Response Response            `json:"response"`
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body"`
+}
+
+type Response struct {
+	StatusCode  int           `json:"status_code"`
+	Status      string        `json:"status"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Headers     http.Header   `json:"headers,omitempty"`
+	ContentType string        `json:"content_type,omitempty"`
+	Body        Body          `json:"body"`
+	// Issues are the departures from the spec found when it was received.
+	Issues []request.ContractIssue `json:"issues,omitempty"`
+}
+
+// Body keeps text bodies readable in the file and falls back to base64 for
+// binary ones. Truncated is set when the body was cut at maxBodyBytes.
+type Body struct {
+	Text      string `json:"text,omitempty"`
+	Base64    []byte `json:"base64,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func newBody(b []byte) Body {
+	var body Body
+	if len(b) > maxBodyBytes {
+		b, body.Truncated = b[:maxBodyBytes], true
+	}
+	if utf8.Valid(b) {
+		body.Text = string(b)
+	} else {
+		body.Base64 = b
+	}
+	return body
+}
+
+func (b Body) Bytes() []byte {
+	if b.Base64 != nil {
+		return b.Base64
+	}
+	return []byte(b.Text)
+}
+
+// redacted stands in for secret values, which the history does not keep.
+const redacted = "REDACTED"
+
+// NewEntry records result for the endpoint. input holds the values as
+// entered, see request.PresetValues. The history file is outside the
+// secret store, so the values of input's secret fields and of credential
+// headers are left out; they have to be entered again to resend.
+func NewEntry(specID, environment string, ep request.Endpoint, server string, input params.StoredParams, result request.ResultInfo) Entry {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	now := time.Now()
+	input.Name, input.ID, input.Local, input.RecordedAt = "", "", false, now
+	reqBody := newBody([]byte(result.Request.Body))
+	if input.IsSecret(params.SecretBody) {
+		reqBody = Body{}
+	}
+	return Entry{
+		ID:          hex.EncodeToString(id),
+		Time:        now,
+		SpecID:      specID,
+		Environment: environment,
+		Method:      ep.Method,
+		Path:        ep.Path,
+		Server:      server,
+		Input:       input.WithoutSecrets(),
+		Request: Request{
+			Method:  result.Request.Method,
+			URL:     redactURL(result.Request.URL, input),
+			Headers: redactHeaders(result.Request.Headers, input),
+			Body:    reqBody,
+		},
+		Response: Response{
+			StatusCode:  result.Response.StatusCode,
+			Status:      result.Response.Status,
+			Elapsed:     result.Response.Elapsed,
+			Headers:     redactHeaders(result.Response.Headers, params.StoredParams{}),
+			ContentType: result.Response.ContentType,
+			Body:        newBody(result.Response.RawBody),
+			Issues:      result.Response.Issues,
+		},
+	}
+}
+
+// redactHeaders replaces the values of credential headers and of headers
+// input marks secret.
+func redactHeaders(h http.Header, input params.StoredParams) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		secret := params.LooksSecret(name) || strings.Contains(strings.ToLower(name), "cookie") ||
+			slices.ContainsFunc(input.Secrets, func(ref string) bool {
+				kind, field, _ := strings.Cut(ref, ".")
+				return (kind == "header" || kind == "custom_headers") && strings.EqualFold(field, name)
+			})
+		if !secret {
+			out[name] = slices.Clone(values)
+			continue
+		}
+		out[name] = make([]string, len(values))
+		for i := range values {
+			out[name][i] = redacted
+		}
+	}
+	return out
+}
+
+// redactURL replaces the values of secret path and query parameters, and
+// of query parameters named like credentials, in the request URL.
+func redactURL(raw string, input params.StoredParams) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	segments := strings.Split(u.EscapedPath(), "/")
+	for name, v := range input.Path {
+		if v == "" || !input.IsSecret(params.SecretRef("path", name)) {
+			continue
+		}
+		for i, seg := range segments {
+			if seg == url.PathEscape(v) {
+				segments[i] = redacted
+			}
+		}
+	}
+	escaped := strings.Join(segments, "/")
+	if escaped != u.EscapedPath() {
+		u.Path, _ = url.PathUnescape(escaped)
+		u.RawPath = escaped
+	}
+
+	q := u.Query()
+	changed := false
+	for name, values := range q {
+		if !params.LooksSecret(name) && !input.IsSecret(params.SecretRef("query", name)) {
+			continue
+		}
+		for i := range values {
+			values[i] = redacted
+		}
+		changed = true
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// Result turns the entry back into what request.Send returned, for the
+// output renderers.
+func (e Entry) Result() request.ResultInfo {
+	raw := e.Response.Body.Bytes()
+	var jsonBody any
+	if strings.Contains(e.Response.ContentType, "application/json") ||
+		(len(raw) > 0 && (raw[0] == '{' || raw[0] == '[')) {
+		var v any
+		if err := json.Unmarshal(raw, &v); err == nil {
+			jsonBody = v
+		}
+	}
+	return request.ResultInfo{
+		Request: request.RequestInfo{
+			Method:  e.Request.Method,
+			URL:     e.Request.URL,
+			Headers: e.Request.Headers,
+			Body:    string(e.Request.Body.Bytes()),
+		},
+		Response: request.ResponseInfo{
+			StatusCode:  e.Response.StatusCode,
+			Status:      e.Response.Status,
+			Elapsed:     e.Response.Elapsed,
+			Headers:     e.Response.Headers,
+			ContentType: e.Response.ContentType,
+			RawBody:     raw,
+			JSONBody:    jsonBody,
+			Issues:      e.Response.Issues,
+		},
+	}
+}
+
+// Log is the history file. Entries are appended as JSON lines, so
+// concurrent clyst instances do not overwrite each other's entries.
+type Log struct {
+	path string
+}
+
+// DefaultPath returns <user cache dir>/clyst/history.jsonl. The history
+// holds request values, so it is kept out of the project directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "clyst", historyFilename), nil
+}
+
+// Open returns the log at path. An empty path gives a log that records
+// nothing.
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+// Append adds e to the log, rotating the file once it is full.
+func (l *Log) Append(e Entry) error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(line)) > maxFileBytes {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	// One write per entry keeps lines from interleaving.
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the entries of the spec, newest first. Lines that cannot
+// be read, such as one cut short by a crash, are skipped.
+func (l *Log) Entries(specID string) ([]Entry, error) {
+	if l == nil || l.path == "" {
+		return nil, nil
+	}
+	var entries []Entry
+	for _, path := range []string{l.path + ".1", l.path} {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, maxFileBytes)
+		for sc.Scan() {
+			var e Entry
+			if json.Unmarshal(sc.Bytes(), &e) != nil || e.SpecID != specID {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
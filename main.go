@@ -1,58 +1,104 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
 	"strings"
+	"sync"
 
+	"github.com/atolix/clyst/auth"
 	"github.com/atolix/clyst/config"
+	"github.com/atolix/clyst/history"
 	"github.com/atolix/clyst/output"
+	"github.com/atolix/clyst/params"
 	"github.com/atolix/clyst/request"
 	"github.com/atolix/clyst/spec"
 	"github.com/atolix/clyst/tui"
-	"github.com/atolix/clyst/tui/selector"
-
-	"github.com/charmbracelet/bubbles/list"
 )
 
 func main() {
-	names := specNamesOrExit()
+	if len(os.Args) > 1 && (os.Args[1] == "call" || os.Args[1] == "run") {
+		os.Exit(runCall(os.Args[1], os.Args[2:]))
+	}
 
-Outer:
-	for {
-		specPath, ok := discoverSpecPath(names)
-		if !ok {
-			return
-		}
+	specSource := flag.String("spec", "", "spec file, http(s) URL, or - to read from stdin")
+	outputFlag := flag.String("output", string(output.ModePretty), "response output: pretty|json|raw|body|headers|status")
+	flag.Parse()
 
-		specDoc := mustLoadSpec(specPath)
-		if specDoc == nil {
-			return
-		}
+	mode, err := output.ParseMode(*outputFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-		switchSpec, exit := runEndpointSession(specDoc)
-		if exit {
-			return
-		}
-		if switchSpec {
-			continue Outer
+	cfg, names := configOrExit()
+	s := &session{cfg: cfg, output: mode}
+
+	sources := []string{*specSource}
+	if *specSource == "" {
+		sources = discoverSpecPaths(names)
+	}
+
+	opts := tui.AppOptions{
+		Specs:        sources,
+		Environments: cfg.EnvironmentNames(),
+		// The viewer needs a terminal; other output modes print the
+		// response and end the session as before.
+		ExitAfterSend: mode != output.ModePretty || !isTerminal(os.Stdout),
+	}
+	if len(opts.Environments) > 0 {
+		opts.Environment = opts.Environments[0]
+	}
+	// With stdout redirected (clyst --output json | jq) the screens go to
+	// the terminal, so stdout only gets the printed response.
+	if !isTerminal(os.Stdout) {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			opts.Output = os.Stderr
+		} else {
+			defer tty.Close()
+			opts.Output = tty
 		}
 	}
+
+	res, err := tui.RunApp(s, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "TUI running error:", err)
+		os.Exit(1)
+	}
+	if res.Err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", res.Err)
+		os.Exit(1)
+	}
+	if res.Warning != nil {
+		fmt.Fprintln(os.Stderr, "warning:", res.Warning)
+	}
+	// Leave the last response in the terminal.
+	if res.Last != nil {
+		s.print(*res.Last)
+	}
 }
 
-func specNamesOrExit() []string {
-	cfg, _ := config.Load()
+func configOrExit() (*config.Config, []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Println("Config error:", err)
+		os.Exit(1)
+	}
 	names, err := config.DefineSpecNames(cfg)
 	if err != nil {
 		fmt.Println("Config error:", err)
 		os.Exit(1)
 	}
-	return names
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return cfg, names
 }
 
-func discoverSpecPath(names []string) (string, bool) {
+func discoverSpecPaths(names []string) []string {
 	found, err := spec.DiscoverSpecFiles(".", names)
 	if err != nil {
 		panic(err)
@@ -62,131 +108,150 @@ func discoverSpecPath(names []string) (string, bool) {
 		fmt.Printf("No spec file found. Looked for: %s\n", strings.Join(names, ", "))
 		os.Exit(1)
 	}
+	return found
+}
 
-	specPath := found[0]
-	if len(found) == 1 {
-		return specPath, true
+func specLoadOptions(cfg *config.Config) spec.LoadOptions {
+	return spec.LoadOptions{
+		AllowRemoteRefs: cfg.AllowRemoteRefs,
+		CacheDir:        spec.DefaultCacheDir(),
 	}
+}
 
-	opts := make([]selector.SpecItem, 0, len(found))
-	for _, p := range found {
-		opts = append(opts, selector.SpecItem{
-			TitleText: p,
-			DescText:  filepath.Dir(p),
-			Value:     p,
-		})
-	}
+// session implements tui.Backend with the config and the output mode. It
+// reads the credential store once per spec; the history is opened on each
+// use.
+type session struct {
+	cfg    *config.Config
+	output output.Mode
 
-	selected, err := selector.SelectSpec("Select an OpenAPI spec", opts)
-	if err != nil {
-		fmt.Println("TUI running error:", err)
-		os.Exit(1)
-	}
+	mu    sync.Mutex
+	authz map[string]*auth.Authorizer
+}
 
-	if strings.TrimSpace(selected) == "" {
-		return "", false
-	}
+func (s *session) LoadSpec(source string) (*spec.OpenApiSpec, error) {
+	return spec.LoadSource(source, specLoadOptions(s.cfg))
+}
 
-	return selected, true
+// Servers prefers the environment's base URL over the spec's servers.
+func (s *session) Servers(ep request.Endpoint, environment string) []spec.Server {
+	if env := s.cfg.Environment(environment); env != nil && env.BaseURL != "" {
+		return []spec.Server{{URL: request.Interpolate(env.BaseURL, env.Variables), Description: environment}}
+	}
+	return ep.Operation.Servers
 }
 
-func mustLoadSpec(path string) *spec.OpenApiSpec {
-	doc, err := spec.Load(path)
-	if doc == nil {
-		return nil
+// Authorizer reports a store that cannot be read only the first time, as
+// the authorizer then keeps credentials for the session.
+func (s *session) Authorizer(doc *spec.OpenApiSpec) (*auth.Authorizer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if authz, ok := s.authz[doc.ID()]; ok {
+		return authz, nil
 	}
-	if err != nil {
-		panic(err)
+	authz, err := loadAuthorizer(doc)
+	if s.authz == nil {
+		s.authz = map[string]*auth.Authorizer{}
 	}
-	return doc
+	s.authz[doc.ID()] = authz
+	return authz, err
 }
 
-func runEndpointSession(doc *spec.OpenApiSpec) (bool, bool) {
-	items := buildEndpointItems(doc)
-
-EndpointLoop:
-	for {
-		runRes, err := selector.RunEndpoints(items)
-		if err != nil {
-			panic(err)
-		}
-
-		if runRes.SwitchSpecSelect {
-			return true, false
-		}
-
-		if runRes.Selected == nil {
-			return false, false
-		}
-
-		ep := request.Endpoint{
-			Method:    runRes.Selected.Method,
-			Path:      runRes.Selected.Path,
-			Operation: runRes.Selected.Operation,
-		}
-
-		baseURL := doc.BaseURL
-		if strings.TrimSpace(baseURL) == "" {
-			fmt.Println("Not found BaseURL")
-			os.Exit(1)
-		}
+// Prepare fills in the environment's variables and headers as it is
+// configured now.
+func (s *session) Prepare(req tui.Request) request.InputProvider {
+	if env := s.cfg.Environment(req.Environment); env != nil {
+		return request.EnvProvider{InputProvider: req.Input, Variables: env.Variables, Headers: env.Headers, Parameters: req.Endpoint.Operation.Parameters}
+	}
+	return req.Input
+}
 
-		tuiInput := &tui.TUIInput{Endpoint: ep}
-		input, canceled, err := request.AssembleInput(baseURL, ep, tuiInput)
-		if err != nil {
-			panic(err)
-		}
+// Send sends and records the request in its environment, see Prepare.
+func (s *session) Send(ctx context.Context, doc *spec.OpenApiSpec, req tui.Request) (request.ResultInfo, error, error) {
+	provider := s.Prepare(req)
+	input, _, err := request.AssembleInput(req.Server, req.Endpoint, provider)
+	if err != nil {
+		return request.ResultInfo{}, nil, err
+	}
+	values := request.PresetValues(req.Endpoint, req.Input)
+	authz, _ := s.Authorizer(doc)
+	return sendRecorded(ctx, doc, req.Environment, req.Endpoint, authz, serverBase(req.Server, provider), input, values)
+}
 
-		if canceled {
-			if tuiInput.ShouldReselectEndpoint() {
-				continue EndpointLoop
-			}
-			return false, true
-		}
+func (s *session) History(doc *spec.OpenApiSpec) ([]history.Entry, error) {
+	return openHistory().Entries(doc.ID())
+}
 
-		result, err := request.Send(ep, input)
-		if err != nil {
-			panic(err)
-		}
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-		handlePresetRecording(ep, tuiInput)
+func (s *session) print(result request.ResultInfo) {
+	if err := output.Write(os.Stdout, result, s.output, output.ColorEnabled(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write output:", err)
+	}
+	warnIssues(result, s.output)
+}
 
-		fmt.Println(output.Render(result))
-		return false, true
+// warnIssues reports where the response departs from the spec on stderr.
+// The pretty output already shows them.
+func warnIssues(result request.ResultInfo, mode output.Mode) {
+	if mode == output.ModePretty {
+		return
+	}
+	for _, issue := range result.Response.Issues {
+		fmt.Fprintln(os.Stderr, "warning: response", issue)
 	}
 }
 
-func buildEndpointItems(doc *spec.OpenApiSpec) []list.Item {
-	var endpoints []selector.EndpointItem
-	for path, methods := range doc.Paths {
-		for method, op := range methods {
-			endpoints = append(endpoints, selector.EndpointItem{
-				Method:    method,
-				Path:      path,
-				Operation: op,
-			})
-		}
+// sendRecorded sends the request and appends it to the history. values are
+// the inputs as entered (see request.PresetValues), so the entry can be
+// sent again or saved as a preset. A failure to record the request is
+// returned as warning; the caller reports it where it fits, as stderr
+// belongs to the TUI while it runs.
+func sendRecorded(ctx context.Context, doc *spec.OpenApiSpec, environment string, ep request.Endpoint, authz *auth.Authorizer, server string, input request.InputResult, values params.StoredParams) (result request.ResultInfo, warning, err error) {
+	result, err = request.Sender{Auth: authz}.SendContext(ctx, ep, input)
+	if err != nil {
+		return result, nil, err
 	}
+	entry := history.NewEntry(doc.ID(), environment, ep, server, values, result)
+	if err := openHistory().Append(entry); err != nil {
+		warning = fmt.Errorf("failed to record history: %w", err)
+	}
+	return result, warning, nil
+}
 
-	sort.Slice(endpoints, func(i, j int) bool {
-		if endpoints[i].Method == endpoints[j].Method {
-			return endpoints[i].Path < endpoints[j].Path
-		}
-		return endpoints[i].Method < endpoints[j].Method
-	})
+// openHistory opens the user's request history, falling back to one that
+// records nothing when there is no cache directory.
+func openHistory() *history.Log {
+	path, err := history.DefaultPath()
+	if err != nil {
+		path = ""
+	}
+	return history.Open(path)
+}
 
-	items := make([]list.Item, 0, len(endpoints))
-	for _, ep := range endpoints {
-		items = append(items, ep)
+// serverBase is the server URL with the provider's variable values.
+func serverBase(server spec.Server, provider request.InputProvider) string {
+	vars := make(map[string]string, len(server.Variables))
+	for name, v := range server.Variables {
+		vars[name] = provider.GetServerVariable(name, v)
 	}
-	return items
+	return strings.TrimRight(server.Expand(vars), "/")
 }
 
-func handlePresetRecording(ep request.Endpoint, tuiInput *tui.TUIInput) {
-	if !tuiInput.ShouldRecord() {
-		return
+// loadAuthorizer reads the user's credential store. When it cannot, it
+// returns the error with an in-memory store so a broken file does not
+// block sending.
+func loadAuthorizer(doc *spec.OpenApiSpec) (*auth.Authorizer, error) {
+	path, err := auth.DefaultStorePath()
+	if err != nil {
+		path = ""
 	}
-	if err := request.SavePreset(".", ep, tuiInput); err != nil {
-		fmt.Println("failed to save params:", err)
+	store, err := auth.LoadStore(path)
+	if err != nil {
+		store, _ = auth.LoadStore("")
 	}
+	return auth.NewAuthorizer(doc, store), err
 }
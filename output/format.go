@@ -0,0 +1,170 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/atolix/clyst/request"
+)
+
+// Mode selects what Write prints.
+type Mode string
+
+const (
+	// ModePretty is the boxed request/response view.
+	ModePretty Mode = "pretty"
+	// ModeJSON is a JSON envelope of the request and response.
+	ModeJSON Mode = "json"
+	// ModeRaw is the response body bytes exactly as received.
+	ModeRaw Mode = "raw"
+	// ModeBody is the response body, indented when it is JSON.
+	ModeBody Mode = "body"
+	// ModeHeaders is the response headers, one "Name: value" per line.
+	ModeHeaders Mode = "headers"
+	// ModeStatus is the response status code.
+	ModeStatus Mode = "status"
+)
+
+var Modes = []Mode{ModePretty, ModeJSON, ModeRaw, ModeBody, ModeHeaders, ModeStatus}
+
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	names := make([]string, len(Modes))
+	for i, m := range Modes {
+		names[i] = string(m)
+	}
+	return "", fmt.Errorf("unknown output mode %q (want %s)", s, strings.Join(names, "|"))
+}
+
+// ColorEnabled reports whether f is a terminal and NO_COLOR is not set.
+func ColorEnabled(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Write prints result to w in the given mode. color only affects the
+// pretty and body modes; the others never contain escape codes.
+func Write(w io.Writer, result request.ResultInfo, mode Mode, color bool) error {
+	var err error
+	switch mode {
+	case ModePretty, "":
+		_, err = fmt.Fprintln(w, render(result, color))
+	case ModeJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(newEnvelope(result))
+	case ModeRaw:
+		_, err = w.Write(result.Response.RawBody)
+	case ModeBody:
+		body, lexer := laxerResponseBody(result)
+		_, err = fmt.Fprintln(w, highlight(body, lexer, color))
+	case ModeHeaders:
+		_, err = io.WriteString(w, headerLines(result.Response.Headers))
+	case ModeStatus:
+		_, err = fmt.Fprintln(w, result.Response.StatusCode)
+	default:
+		err = fmt.Errorf("unknown output mode %q", mode)
+	}
+	return err
+}
+
+type envelope struct {
+	Request  envelopeRequest  `json:"request"`
+	Response envelopeResponse `json:"response"`
+}
+
+type envelopeRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers"`
+	Body    any                 `json:"body,omitempty"`
+}
+
+type envelopeResponse struct {
+	Status      int                 `json:"status"`
+	StatusText  string              `json:"status_text"`
+	ElapsedMS   float64             `json:"elapsed_ms"`
+	Headers     map[string][]string `json:"headers"`
+	ContentType string              `json:"content_type,omitempty"`
+	// Body is the decoded JSON value, or the body as a string. Bodies that
+	// are not UTF-8 go to BodyBase64 instead.
+	Body       any    `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+	// Issues are the departures from the spec, see request.CheckResponse.
+	Issues []request.ContractIssue `json:"issues,omitempty"`
+}
+
+func newEnvelope(result request.ResultInfo) envelope {
+	env := envelope{
+		Request: envelopeRequest{
+			Method:  strings.ToUpper(result.Request.Method),
+			URL:     result.Request.URL,
+			Headers: orEmpty(result.Request.Headers),
+		},
+		Response: envelopeResponse{
+			Status:      result.Response.StatusCode,
+			StatusText:  httpStatusText(result.Response.Status),
+			ElapsedMS:   float64(result.Response.Elapsed.Microseconds()) / 1000,
+			Headers:     orEmpty(result.Response.Headers),
+			ContentType: result.Response.ContentType,
+			Issues:      result.Response.Issues,
+		},
+	}
+
+	if b := result.Request.Body; strings.TrimSpace(b) != "" {
+		var v any
+		if json.Unmarshal([]byte(b), &v) == nil {
+			env.Request.Body = v
+		} else {
+			env.Request.Body = b
+		}
+	}
+
+	raw := result.Response.RawBody
+	switch {
+	case result.Response.JSONBody != nil:
+		env.Response.Body = result.Response.JSONBody
+	case len(raw) == 0:
+	case utf8.Valid(raw):
+		env.Response.Body = string(raw)
+	default:
+		env.Response.BodyBase64 = raw
+	}
+	return env
+}
+
+func orEmpty(h map[string][]string) map[string][]string {
+	if h == nil {
+		return map[string][]string{}
+	}
+	return h
+}
+
+func headerLines(h map[string][]string) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(&buf, "%s: %s\n", k, v)
+		}
+	}
+	return buf.String()
+}
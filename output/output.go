@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/atolix/clyst/request"
@@ -20,6 +21,9 @@ type styles struct {
 	value   lipgloss.Style
 	box     lipgloss.Style
 	codeBox lipgloss.Style
+	issue   lipgloss.Style
+	// color enables syntax highlighting of bodies.
+	color bool
 }
 
 func defaultStyles() styles {
@@ -29,6 +33,7 @@ func defaultStyles() styles {
 		value:   lipgloss.NewStyle().Foreground(theme.Text),
 		box:     lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(theme.Border).Padding(1, 2),
 		codeBox: lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(theme.CodeBorder).Padding(0, 1).MarginTop(0),
+		issue:   lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b")),
 	}
 }
 
@@ -47,7 +52,12 @@ var basicResponseHeaders = []string{
 }
 
 func Render(result request.ResultInfo) string {
+	return render(result, true)
+}
+
+func render(result request.ResultInfo, color bool) string {
 	s := defaultStyles()
+	s.color = color
 	reqBox := renderRequestBox(result, s)
 	bodyStr, lexer := laxerResponseBody(result)
 	headers := renderHeaders(result, s)
@@ -61,13 +71,22 @@ func renderRequestBox(result request.ResultInfo, s styles) string {
 		s.label.Render("Method:") + " " + s.value.Render(strings.ToUpper(result.Request.Method)),
 		s.label.Render("URL:") + "    " + s.value.Render(result.Request.URL),
 	}
+	if len(result.Request.Headers) > 0 {
+		keys := make([]string, 0, len(result.Request.Headers))
+		for k := range result.Request.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		lines = append(lines, s.label.Render("Headers:"))
+		for _, k := range keys {
+			lines = append(lines, "  "+s.label.Render(k+":")+" "+s.value.Render(strings.Join(result.Request.Headers[k], ", ")))
+		}
+	}
 	if strings.TrimSpace(result.Request.Body) != "" {
 		var pretty bytes.Buffer
 		var rendered string
 		if json.Indent(&pretty, []byte(result.Request.Body), "", "  ") == nil {
-			var buf bytes.Buffer
-			_ = quick.Highlight(&buf, pretty.String(), "json", "terminal", "github")
-			rendered = buf.String()
+			rendered = highlight(pretty.String(), "json", s.color)
 		} else {
 			rendered = result.Request.Body
 		}
@@ -112,12 +131,6 @@ func renderHeaders(result request.ResultInfo, s styles) string {
 }
 
 func renderResponseBox(result request.ResultInfo, headersSection, bodyStr, lexer string, s styles) string {
-	var bodyBuf bytes.Buffer
-	if err := quick.Highlight(&bodyBuf, bodyStr, lexer, "terminal", "github"); err != nil {
-		bodyBuf.Reset()
-		bodyBuf.WriteString(bodyStr)
-	}
-
 	meta := []string{
 		s.label.Render("Status:") + " " + s.value.Render(fmt.Sprintf("%d %s", result.Response.StatusCode, httpStatusText(result.Response.Status))),
 		s.label.Render("Time:") + "   " + s.value.Render(result.Response.Elapsed.String()),
@@ -129,11 +142,31 @@ func renderResponseBox(result request.ResultInfo, headersSection, bodyStr, lexer
 	if headersSection != "" {
 		content += "\n" + s.label.Render("Headers:") + "\n" + headersSection
 	}
-	content += "\n" + s.label.Render("Body:") + "\n" + s.codeBox.Render(bodyBuf.String())
+	content += "\n" + s.label.Render("Body:") + "\n" + s.codeBox.Render(highlight(bodyStr, lexer, s.color))
+	if issues := result.Response.Issues; len(issues) > 0 {
+		lines := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			lines = append(lines, "  "+s.issue.Render("✗ "+issue.String()))
+		}
+		content += "\n" + s.label.Render("Spec mismatches:") + "\n" + strings.Join(lines, "\n")
+	}
 
 	return s.title.Render("Response") + "\n" + s.box.Render(content)
 }
 
+// highlight colors code for the terminal, or returns it unchanged when
+// color is off or the lexer fails.
+func highlight(code, lexer string, color bool) string {
+	if !color {
+		return code
+	}
+	var buf bytes.Buffer
+	if err := quick.Highlight(&buf, code, lexer, "terminal", "github"); err != nil {
+		return code
+	}
+	return buf.String()
+}
+
 func httpStatusText(status string) string {
 	parts := strings.SplitN(status, " ", 2)
 	if len(parts) == 2 {
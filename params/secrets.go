@@ -0,0 +1,298 @@
+package params
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// PassphraseEnv enables the encrypted secrets file. Without it secret
+	// values are kept in the git-ignored local file.
+	PassphraseEnv   = "CLYST_SECRETS_PASSPHRASE"
+	secretsFilename = ".clyst_params.secrets"
+
+	kdfIterations = 600_000
+	secretsAAD    = "clyst-secrets"
+)
+
+// SecretBody marks the whole request body as secret.
+const SecretBody = "body"
+
+// SecretRef names a preset field for StoredParams.Secrets, e.g.
+// "header.Authorization". kind is one of path, query, header, cookie and
+// custom_headers.
+func SecretRef(kind, name string) string {
+	return kind + "." + name
+}
+
+// IsSecret reports whether the field ref is marked secret.
+func (p StoredParams) IsSecret(ref string) bool {
+	return slices.Contains(p.Secrets, ref)
+}
+
+var secretNamePattern = regexp.MustCompile(`(?i)authorization|token|secret|passw(or)?d|api[-_]?key|session|credential|signature`)
+
+// LooksSecret suggests marking a parameter or header as secret by its name.
+func LooksSecret(name string) bool {
+	return secretNamePattern.MatchString(name)
+}
+
+// SecretsByName lists the fields of p whose names look secret, the marking
+// the form starts from when no preset says otherwise.
+func (p StoredParams) SecretsByName() []string {
+	var refs []string
+	for _, kind := range []string{"path", "query", "header", "cookie", "custom_headers"} {
+		for name := range *p.fieldMap(kind) {
+			if LooksSecret(name) {
+				refs = append(refs, SecretRef(kind, name))
+			}
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// WithoutSecrets returns a copy of p without the values of its secret
+// fields, for copies kept outside the secret store such as the history.
+func (p StoredParams) WithoutSecrets() StoredParams {
+	p = clonePreset(p)
+	extractSecrets(&p)
+	return p
+}
+
+func (p *StoredParams) fieldMap(kind string) *map[string]string {
+	switch kind {
+	case "path":
+		return &p.Path
+	case "query":
+		return &p.Query
+	case "header":
+		return &p.Header
+	case "cookie":
+		return &p.Cookie
+	case "custom_headers":
+		return &p.CustomHeaders
+	}
+	return nil
+}
+
+// extractSecrets removes the secret values from p and returns them by ref.
+func extractSecrets(p *StoredParams) map[string]string {
+	out := map[string]string{}
+	for _, ref := range p.Secrets {
+		if ref == SecretBody {
+			if p.Body != "" {
+				out[ref] = p.Body
+			}
+			p.Body = ""
+			continue
+		}
+		kind, name, _ := strings.Cut(ref, ".")
+		m := p.fieldMap(kind)
+		if m == nil {
+			continue
+		}
+		if v, ok := (*m)[name]; ok {
+			out[ref] = v
+			delete(*m, name)
+		}
+	}
+	return out
+}
+
+// fillSecrets puts values taken by extractSecrets back into p.
+func fillSecrets(p *StoredParams, values map[string]string) {
+	for ref, v := range values {
+		if ref == SecretBody {
+			p.Body = v
+			continue
+		}
+		kind, name, _ := strings.Cut(ref, ".")
+		m := p.fieldMap(kind)
+		if m == nil {
+			continue
+		}
+		if *m == nil {
+			*m = map[string]string{}
+		}
+		(*m)[name] = v
+	}
+}
+
+// secretMap holds secret values by spec ID, preset ID and field ref.
+type secretMap map[string]map[string]map[string]string
+
+// secretStore keeps the secret values of shared presets, either in the
+// local file or, with a passphrase, in an AES-GCM encrypted file.
+type secretStore struct {
+	values     secretMap
+	passphrase string
+	salt       []byte
+	iterations int
+	key        []byte
+}
+
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// loadSecrets reads the secrets for the current passphrase setting. prev
+// lets a reload reuse the derived key instead of running the KDF again.
+func loadSecrets(dir string, local secretMap, prev *secretStore) (*secretStore, error) {
+	s := &secretStore{values: secretMap{}, passphrase: os.Getenv(PassphraseEnv)}
+	merge(s.values, local)
+	if s.passphrase == "" {
+		return s, nil
+	}
+
+	path := filepath.Join(dir, secretsFilename)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f encryptedFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", secretsFilename, err)
+	}
+	if f.KDF != "pbkdf2-sha256" || f.Iterations <= 0 {
+		return nil, fmt.Errorf("%s: unsupported kdf %q", secretsFilename, f.KDF)
+	}
+	s.salt, s.iterations = f.Salt, f.Iterations
+	if prev != nil && prev.passphrase == s.passphrase && bytes.Equal(prev.salt, f.Salt) && prev.iterations == f.Iterations {
+		s.key = prev.key
+	} else if s.key, err = pbkdf2.Key(sha256.New, s.passphrase, f.Salt, f.Iterations, 32); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, []byte(secretsAAD))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: wrong %s?", secretsFilename, PassphraseEnv)
+	}
+	var values secretMap
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", secretsFilename, err)
+	}
+	// Values still in the local file move into the encrypted one on save.
+	merge(values, s.values)
+	s.values = values
+	return s, nil
+}
+
+func (s *secretStore) get(spec, id string) map[string]string {
+	return s.values[spec][id]
+}
+
+// set stores the values of a preset; empty values remove it.
+func (s *secretStore) set(spec, id string, values map[string]string) {
+	if len(values) == 0 {
+		delete(s.values[spec], id)
+		if len(s.values[spec]) == 0 {
+			delete(s.values, spec)
+		}
+		return
+	}
+	if s.values[spec] == nil {
+		s.values[spec] = map[string]map[string]string{}
+	}
+	s.values[spec][id] = values
+}
+
+// save writes the values to the encrypted file when a passphrase is set,
+// otherwise into local.
+func (s *secretStore) save(dir string, local *fileData) error {
+	if s.passphrase == "" {
+		local.Secrets = s.values
+		return nil
+	}
+	local.Secrets = nil
+
+	path := filepath.Join(dir, secretsFilename)
+	if len(s.values) == 0 {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	if s.key == nil {
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return err
+		}
+		s.iterations = kdfIterations
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, s.salt, s.iterations, 32)
+		if err != nil {
+			return err
+		}
+		s.key = key
+	}
+
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(encryptedFile{
+		Version:    1,
+		KDF:        "pbkdf2-sha256",
+		Iterations: s.iterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, plain, []byte(secretsAAD)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, payload, 0o600)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func merge(dst, src secretMap) {
+	for spec, presets := range src {
+		if dst[spec] == nil {
+			dst[spec] = map[string]map[string]string{}
+		}
+		for id, values := range presets {
+			if _, ok := dst[spec][id]; !ok {
+				dst[spec][id] = values
+			}
+		}
+	}
+}
@@ -1,94 +1,531 @@
 package params
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
 
-const defaultFilename = ".clyst_params"
+const (
+	// defaultFilename holds presets meant to be committed and shared.
+	defaultFilename = ".clyst_params"
+	// localFilename holds personal presets and, unless they are encrypted,
+	// the secret values of shared presets. It should be git-ignored.
+	localFilename = ".clyst_params.local"
+)
+
+// fileVersion is the layout written by this version. Bump it when the
+// layout changes and migrate older files in decodeFile.
+const fileVersion = 3
 
 type StoredParams struct {
-	Path       map[string]string `json:"path,omitempty"`
-	Query      map[string]string `json:"query,omitempty"`
-	Body       string            `json:"body,omitempty"`
-	RecordedAt time.Time         `json:"recorded_at,omitempty"`
+	// ID identifies the preset across files; it is assigned when saved.
+	ID string `json:"id,omitempty"`
+	// Name is chosen by the user; presets without one are shown by date.
+	Name          string            `json:"name,omitempty"`
+	Path          map[string]string `json:"path,omitempty"`
+	Query         map[string]string `json:"query,omitempty"`
+	Header        map[string]string `json:"header,omitempty"`
+	Cookie        map[string]string `json:"cookie,omitempty"`
+	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	// Secrets lists the fields (see SecretRef) whose values are kept out of
+	// the shared file.
+	Secrets    []string  `json:"secrets,omitempty"`
+	RecordedAt time.Time `json:"recorded_at,omitempty"`
+	// Local is set for presets from the personal file.
+	Local bool `json:"-"`
 }
 
+// Store holds the presets of one spec, merged from the shared and the local
+// file. Each file keeps the presets of every spec used in the directory,
+// keyed by spec ID, so specs that share an endpoint such as `GET /health`
+// do not see each other's presets.
 type Store struct {
-	path string
-	data map[string][]StoredParams
+	dir     string
+	spec    string
+	shared  fileData
+	local   fileData
+	secrets *secretStore
 }
 
-func Load(dir string) (*Store, error) {
+// fileData is the on-disk layout. Unscoped holds presets written before
+// presets were namespaced. Every spec lists them; a change to the endpoint
+// moves them into the changing spec only while the file names no other
+// spec, so one spec cannot take over presets another one may own.
+type fileData struct {
+	Version  int                                  `json:"version"`
+	Specs    map[string]map[string][]StoredParams `json:"specs"`
+	Unscoped map[string][]StoredParams            `json:"unscoped,omitempty"`
+	// Secrets is only written to the local file.
+	Secrets secretMap `json:"secrets,omitempty"`
+}
+
+// Load opens the preset files in dir for the spec with the given ID (see
+// spec.OpenApiSpec.ID).
+func Load(dir, specID string) (*Store, error) {
 	if strings.TrimSpace(dir) == "" {
 		dir = "."
 	}
-	fp := filepath.Join(dir, defaultFilename)
+	s := &Store{dir: dir, spec: specID}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) reload() error {
+	shared, err := readFile(filepath.Join(s.dir, defaultFilename))
+	if err != nil {
+		return err
+	}
+	local, err := readFile(filepath.Join(s.dir, localFilename))
+	if err != nil {
+		return err
+	}
+	secrets, err := loadSecrets(s.dir, local.Secrets, s.secrets)
+	if err != nil {
+		return err
+	}
+	s.shared, s.local, s.secrets = shared, local, secrets
+	return nil
+}
+
+func readFile(path string) (fileData, error) {
+	data := fileData{Specs: map[string]map[string][]StoredParams{}, Unscoped: map[string][]StoredParams{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || err == nil && len(b) == 0 {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	if err := decodeFile(filepath.Base(path), b, &data); err != nil {
+		return data, err
+	}
+	return data, nil
+}
 
-	data := map[string][]StoredParams{}
-	if b, err := os.ReadFile(fp); err == nil {
-		if len(b) > 0 {
-			if err := json.Unmarshal(b, &data); err != nil {
-				return nil, err
+// decodeFile reads every version of the file and migrates it to the
+// current layout. Version 1 had no version field and mapped "METHOD path"
+// to presets at the top level; those entries become unscoped. Version 2
+// had no ids or secrets, which need no conversion.
+func decodeFile(name string, b []byte, data *fileData) error {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(b, &top); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if raw, ok := top["version"]; ok {
+		if err := json.Unmarshal(raw, &data.Version); err != nil {
+			return fmt.Errorf("%s: version: %w", name, err)
+		}
+		if data.Version > fileVersion {
+			return fmt.Errorf("%s has version %d; this clyst reads up to %d, please upgrade", name, data.Version, fileVersion)
+		}
+	}
+	for key, raw := range top {
+		var err error
+		switch key {
+		case "version":
+		case "specs":
+			err = json.Unmarshal(raw, &data.Specs)
+		case "unscoped":
+			err = json.Unmarshal(raw, &data.Unscoped)
+		case "secrets":
+			err = json.Unmarshal(raw, &data.Secrets)
+		default:
+			var items []StoredParams
+			if err = json.Unmarshal(raw, &items); err == nil {
+				data.Unscoped[key] = append(data.Unscoped[key], items...)
 			}
 		}
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return nil, err
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", name, key, err)
+		}
+	}
+	if data.Specs == nil {
+		data.Specs = map[string]map[string][]StoredParams{}
+	}
+	if data.Unscoped == nil {
+		data.Unscoped = map[string][]StoredParams{}
 	}
+	return nil
+}
+
+// presets lists the presets of key for spec followed by the unscoped ones.
+func (d *fileData) presets(spec, key string) []StoredParams {
+	return append(slices.Clone(d.Specs[spec][key]), d.Unscoped[key]...)
+}
 
-	return &Store{
-		path: fp,
-		data: data,
-	}, nil
+// claims reports whether spec may take over the unscoped presets: the file
+// names no other spec.
+func (d *fileData) claims(spec string) bool {
+	for name, m := range d.Specs {
+		if name != spec && len(m) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// set replaces the presets of key for spec and the unscoped ones,
+// dropping empty maps.
+func (d *fileData) set(spec, key string, items, unscoped []StoredParams) {
+	if len(unscoped) == 0 {
+		delete(d.Unscoped, key)
+	} else {
+		d.Unscoped[key] = unscoped
+	}
+
+	m := d.Specs[spec]
+	if m == nil {
+		m = map[string][]StoredParams{}
+		d.Specs[spec] = m
+	}
+	if len(items) == 0 {
+		delete(m, key)
+	} else {
+		m[key] = items
+	}
+	if len(m) == 0 {
+		delete(d.Specs, spec)
+	}
+}
+
+func (d *fileData) empty() bool {
+	for _, m := range d.Specs {
+		if len(m) > 0 {
+			return false
+		}
+	}
+	return len(d.Unscoped) == 0 && len(d.Secrets) == 0
+}
+
+// combined lists the presets of key, shared ones first, with the secret
+// values of shared presets filled in.
+func (s *Store) combined(key string) []StoredParams {
+	shared := s.shared.presets(s.spec, key)
+	local := s.local.presets(s.spec, key)
+	out := make([]StoredParams, 0, len(shared)+len(local))
+	for _, item := range shared {
+		p := clonePreset(item)
+		fillSecrets(&p, s.secrets.get(s.spec, p.ID))
+		out = append(out, p)
+	}
+	for _, item := range local {
+		p := clonePreset(item)
+		p.Local = true
+		out = append(out, p)
+	}
+	for i := range out {
+		if out[i].ID == "" {
+			out[i].ID = legacyID(out[i])
+		}
+	}
+	return out
+}
+
+// legacyID identifies a preset saved before IDs existed by its name and
+// recording time, so it has the same ID on every load until it is saved
+// with it.
+func legacyID(p StoredParams) string {
+	sum := sha256.Sum256([]byte(p.Name + "\x00" + p.RecordedAt.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// split writes items back to the two files. Secret values of shared presets
+// go to the secret store; secrets of presets no longer present are dropped.
+// Presets that were unscoped stay so unless their file may move them to
+// this spec (see fileData.claims).
+func (s *Store) split(key string, before, items []StoredParams) {
+	unscoped := map[string]bool{}
+	for _, d := range []*fileData{&s.shared, &s.local} {
+		if d.claims(s.spec) {
+			continue
+		}
+		for _, p := range d.Unscoped[key] {
+			if p.ID == "" {
+				p.ID = legacyID(p)
+			}
+			unscoped[p.ID] = true
+		}
+	}
+
+	var shared, local, sharedUnscoped, localUnscoped []StoredParams
+	keep := map[string]bool{}
+	for _, p := range items {
+		if p.ID == "" {
+			p.ID = newID()
+		}
+		keep[p.ID] = true
+		if p.Local {
+			s.secrets.set(s.spec, p.ID, nil)
+			if unscoped[p.ID] {
+				localUnscoped = append(localUnscoped, p)
+			} else {
+				local = append(local, p)
+			}
+			continue
+		}
+		s.secrets.set(s.spec, p.ID, extractSecrets(&p))
+		if unscoped[p.ID] {
+			sharedUnscoped = append(sharedUnscoped, p)
+		} else {
+			shared = append(shared, p)
+		}
+	}
+	for _, p := range before {
+		if !keep[p.ID] {
+			s.secrets.set(s.spec, p.ID, nil)
+		}
+	}
+	s.shared.set(s.spec, key, shared, sharedUnscoped)
+	s.local.set(s.spec, key, local, localUnscoped)
 }
 
 func (s *Store) PresetsFor(method, path string) []StoredParams {
 	if s == nil {
 		return nil
 	}
-	key := keyOf(method, path)
-	items := s.data[key]
-	out := make([]StoredParams, 0, len(items))
-	for _, item := range items {
-		out = append(out, StoredParams{
-			Path:       cloneMap(item.Path),
-			Query:      cloneMap(item.Query),
-			Body:       item.Body,
-			RecordedAt: item.RecordedAt,
-		})
+	return s.combined(keyOf(method, path))
+}
+
+// PresetNamed returns the preset of the endpoint with the given name.
+func (s *Store) PresetNamed(method, path, name string) (StoredParams, bool) {
+	for _, p := range s.PresetsFor(method, path) {
+		if p.Name == name {
+			return p, true
+		}
 	}
-	return out
+	return StoredParams{}, false
 }
 
+// AppendPreset records a preset. A named preset replaces the existing one
+// with the same name, so re-recording keeps a single entry.
 func (s *Store) AppendPreset(method, path string, preset StoredParams) error {
 	if s == nil {
 		return nil
 	}
-	key := keyOf(method, path)
-	preset.Path = cloneMap(preset.Path)
-	preset.Query = cloneMap(preset.Query)
+	preset = clonePreset(preset)
+	preset.Name = strings.TrimSpace(preset.Name)
+	preset.RecordedAt = time.Now()
+	preset.ID = newID()
+	return s.modify(keyOf(method, path), func(items []StoredParams) ([]StoredParams, error) {
+		if preset.Name != "" {
+			for i, existing := range items {
+				if existing.Name == preset.Name {
+					preset.ID = existing.ID
+					items[i] = preset
+					return items, nil
+				}
+			}
+		}
+		return append(items, preset), nil
+	})
+}
+
+// UpdatePreset replaces the preset with the given ID, keeping its
+// position.
+func (s *Store) UpdatePreset(method, path, id string, preset StoredParams) error {
+	if s == nil {
+		return errors.New("no preset store")
+	}
+	preset = clonePreset(preset)
+	preset.Name = strings.TrimSpace(preset.Name)
 	preset.RecordedAt = time.Now()
-	s.data[key] = append(s.data[key], preset)
-	return s.persist()
+	return s.updateID(keyOf(method, path), id, presetLabel(preset), func(items []StoredParams, i int) ([]StoredParams, error) {
+		if err := checkName(items, i, preset.Name); err != nil {
+			return nil, err
+		}
+		preset.ID = items[i].ID
+		items[i] = preset
+		return items, nil
+	})
+}
+
+func (s *Store) DeletePreset(method, path string, index int) error {
+	return s.updateAt(method, path, index, func(items []StoredParams, i int) ([]StoredParams, error) {
+		return slices.Delete(items, i, i+1), nil
+	})
+}
+
+// RenamePreset sets the name of the preset at index. Names are unique per
+// endpoint; an empty name makes the preset unnamed.
+func (s *Store) RenamePreset(method, path string, index int, name string) error {
+	name = strings.TrimSpace(name)
+	return s.updateAt(method, path, index, func(items []StoredParams, i int) ([]StoredParams, error) {
+		if err := checkName(items, i, name); err != nil {
+			return nil, err
+		}
+		items[i].Name = name
+		return items, nil
+	})
+}
+
+// DuplicatePreset inserts a copy of the preset at index right after it.
+// A named copy gets a free "<name> copy" name.
+func (s *Store) DuplicatePreset(method, path string, index int) error {
+	return s.updateAt(method, path, index, func(items []StoredParams, i int) ([]StoredParams, error) {
+		dup := clonePreset(items[i])
+		dup.ID = newID()
+		dup.RecordedAt = time.Now()
+		if dup.Name != "" {
+			base := dup.Name + " copy"
+			dup.Name = base
+			for n := 2; checkName(items, -1, dup.Name) != nil; n++ {
+				dup.Name = fmt.Sprintf("%s %d", base, n)
+			}
+		}
+		return slices.Insert(items, i+1, dup), nil
+	})
+}
+
+// MovePreset moves the preset at index by delta positions and returns its
+// new index. Shared presets always list before local ones, so a preset
+// does not move past that boundary.
+func (s *Store) MovePreset(method, path string, index, delta int) (int, error) {
+	var id string
+	err := s.updateAt(method, path, index, func(items []StoredParams, i int) ([]StoredParams, error) {
+		to := min(max(i+delta, 0), len(items)-1)
+		p := items[i]
+		id = p.ID
+		items = slices.Delete(items, i, i+1)
+		return slices.Insert(items, to, p), nil
+	})
+	if err != nil {
+		return index, err
+	}
+	for i, p := range s.combined(keyOf(method, path)) {
+		if p.ID == id {
+			return i, nil
+		}
+	}
+	return index, nil
+}
+
+// modify applies change to the latest presets of key and writes the files,
+// holding the lock throughout so concurrent instances do not lose each
+// other's changes.
+func (s *Store) modify(key string, change func(items []StoredParams) ([]StoredParams, error)) error {
+	unlock, err := lockFile(filepath.Join(s.dir, defaultFilename))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	before := s.combined(key)
+	items, err := change(slices.Clone(before))
+	if err != nil {
+		return err
+	}
+	s.split(key, before, items)
+	return s.write()
+}
+
+// updateAt runs change on the endpoint's presets with the preset the caller
+// saw at index, see updateID.
+func (s *Store) updateAt(method, path string, index int, change func(items []StoredParams, i int) ([]StoredParams, error)) error {
+	if s == nil {
+		return errors.New("no preset store")
+	}
+	key := keyOf(method, path)
+	items := s.combined(key)
+	if index < 0 || index >= len(items) {
+		return fmt.Errorf("no preset %d for %s", index, key)
+	}
+	return s.updateID(key, items[index].ID, presetLabel(items[index]), change)
+}
+
+// updateID runs change on the presets of key with the index of the preset
+// with the given ID. The preset is looked up after reloading, since other
+// instances may have added or removed presets in the meantime; label names
+// it when it is gone.
+func (s *Store) updateID(key, id, label string, change func(items []StoredParams, i int) ([]StoredParams, error)) error {
+	return s.modify(key, func(items []StoredParams) ([]StoredParams, error) {
+		i := slices.IndexFunc(items, func(p StoredParams) bool { return p.ID == id })
+		if i < 0 {
+			return nil, fmt.Errorf("preset %s was removed by another clyst", label)
+		}
+		return change(items, i)
+	})
 }
 
-func (s *Store) persist() error {
-	payload, err := json.MarshalIndent(s.data, "", "  ")
+func (s *Store) write() error {
+	if err := s.secrets.save(s.dir, &s.local); err != nil {
+		return err
+	}
+	if err := writeData(filepath.Join(s.dir, defaultFilename), &s.shared, 0o644); err != nil {
+		return err
+	}
+	return writeData(filepath.Join(s.dir, localFilename), &s.local, 0o600)
+}
+
+// writeData writes d to path, skipping files that would be created empty.
+func writeData(path string, d *fileData, perm os.FileMode) error {
+	if d.empty() {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	d.Version = fileVersion
+	payload, err := json.MarshalIndent(d, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.path, payload, 0o644)
+	return writeFileAtomic(path, payload, perm)
+}
+
+func presetLabel(p StoredParams) string {
+	if p.Name != "" {
+		return fmt.Sprintf("%q", p.Name)
+	}
+	return "from " + p.RecordedAt.Local().Format(time.DateTime)
+}
+
+func checkName(items []StoredParams, index int, name string) error {
+	if name == "" {
+		return nil
+	}
+	for i, p := range items {
+		if i != index && p.Name == name {
+			return fmt.Errorf("a preset named %q already exists", name)
+		}
+	}
+	return nil
 }
 
 func keyOf(method, path string) string {
 	return strings.ToUpper(method) + " " + path
 }
 
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func clonePreset(p StoredParams) StoredParams {
+	p.Path = cloneMap(p.Path)
+	p.Query = cloneMap(p.Query)
+	p.Header = cloneMap(p.Header)
+	p.Cookie = cloneMap(p.Cookie)
+	p.CustomHeaders = cloneMap(p.CustomHeaders)
+	p.Secrets = slices.Clone(p.Secrets)
+	return p
+}
+
 func cloneMap(src map[string]string) map[string]string {
 	if len(src) == 0 {
 		return nil
@@ -0,0 +1,93 @@
+package params
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// legacyFile is a version 1 preset file: presets of every spec keyed by
+// endpoint alone.
+const legacyFile = `{"GET /health": [{"name": "ping", "recorded_at": "2024-01-02T03:04:05Z"}]}`
+
+func writeLegacy(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, defaultFilename), []byte(legacyFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func load(t *testing.T, dir, specID string) *Store {
+	t.Helper()
+	s, err := Load(dir, specID)
+	if err != nil {
+		t.Fatalf("Load(%s) error = %v", specID, err)
+	}
+	return s
+}
+
+func names(items []StoredParams) []string {
+	out := make([]string, 0, len(items))
+	for _, p := range items {
+		out = append(out, p.Name)
+	}
+	return out
+}
+
+func TestLegacyPresetsStayUnscopedWithTwoSpecs(t *testing.T) {
+	dir := writeLegacy(t)
+	a, b := load(t, dir, "spec-a"), load(t, dir, "spec-b")
+
+	// Reading does not take the presets over: both specs list them.
+	if got := names(a.PresetsFor("get", "/health")); len(got) != 1 || got[0] != "ping" {
+		t.Fatalf("spec-a presets = %v, want [ping]", got)
+	}
+	if got := names(b.PresetsFor("get", "/health")); len(got) != 1 || got[0] != "ping" {
+		t.Fatalf("spec-b presets = %v, want [ping]", got)
+	}
+
+	// Once the file names spec-a, spec-b's change leaves ping unscoped.
+	if err := a.AppendPreset("get", "/users", StoredParams{Name: "list"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AppendPreset("get", "/health", StoredParams{Name: "b-only"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(load(t, dir, "spec-a").PresetsFor("get", "/health")); len(got) != 1 || got[0] != "ping" {
+		t.Errorf("spec-a presets after spec-b's change = %v, want [ping]", got)
+	}
+	if got := names(load(t, dir, "spec-b").PresetsFor("get", "/health")); len(got) != 2 || got[0] != "b-only" || got[1] != "ping" {
+		t.Errorf("spec-b presets = %v, want [b-only ping]", got)
+	}
+	data, err := readFile(filepath.Join(dir, defaultFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(data.Unscoped["GET /health"]); len(got) != 1 || got[0] != "ping" {
+		t.Errorf("unscoped presets = %v, want [ping]", got)
+	}
+}
+
+func TestLegacyPresetsMoveToTheOnlySpec(t *testing.T) {
+	dir := writeLegacy(t)
+	a := load(t, dir, "spec-a")
+	if err := a.AppendPreset("get", "/health", StoredParams{Name: "a-only"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := readFile(filepath.Join(dir, defaultFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Unscoped) != 0 {
+		t.Errorf("unscoped presets = %v, want them moved to spec-a", data.Unscoped)
+	}
+	if got := names(data.Specs["spec-a"]["GET /health"]); len(got) != 2 || got[0] != "ping" || got[1] != "a-only" {
+		t.Errorf("spec-a presets = %v, want [ping a-only]", got)
+	}
+	if got := load(t, dir, "spec-b").PresetsFor("get", "/health"); len(got) != 0 {
+		t.Errorf("spec-b presets = %v, want none", names(got))
+	}
+}
@@ -1,11 +1,11 @@
 package request
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -19,9 +19,10 @@ type Endpoint struct {
 }
 
 type RequestInfo struct {
-	Method string
-	URL    string
-	Body   string
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    string
 }
 
 type ResponseInfo struct {
@@ -32,6 +33,9 @@ type ResponseInfo struct {
 	ContentType string
 	RawBody     []byte
 	JSONBody    any
+	// Issues lists where the response departs from the spec, see
+	// CheckResponse.
+	Issues []ContractIssue
 }
 
 type ResultInfo struct {
@@ -39,17 +43,70 @@ type ResultInfo struct {
 	Response ResponseInfo
 }
 
+// Authorizer adds the credentials an endpoint's security requirement asks
+// for to an outgoing request.
+type Authorizer interface {
+	Authorize(req *http.Request, ep Endpoint) error
+}
+
+// Refresher is implemented by authorizers whose credentials can be renewed.
+// After a 401 the Sender calls Refresh and retries once when it returns true.
+type Refresher interface {
+	Refresh(ctx context.Context, ep Endpoint) (bool, error)
+}
+
+// Sender sends assembled requests. The zero value uses http.DefaultClient
+// and sends requests without credentials.
+type Sender struct {
+	Client *http.Client
+	Auth   Authorizer
+}
+
 func Send(ep Endpoint, input InputResult) (ResultInfo, error) {
-	req, err := http.NewRequest(strings.ToUpper(ep.Method), input.URL, input.Body)
-	if input.Body != nil && strings.TrimSpace(input.RawBody) != "" {
-		req.Header.Set("Content-Type", "application/json")
+	return Sender{}.Send(ep, input)
+}
+
+func (s Sender) Send(ep Endpoint, input InputResult) (ResultInfo, error) {
+	return s.SendContext(context.Background(), ep, input)
+}
+
+// SendContext is Send with a context, which also bounds an interactive
+// login the authorizer runs for the request.
+func (s Sender) SendContext(ctx context.Context, ep Endpoint, input InputResult) (ResultInfo, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, sentHeaders, err := s.newRequest(ctx, ep, input, input.Body)
+	if err != nil {
+		return ResultInfo{}, err
 	}
 
 	start := time.Now()
-	res, err := http.DefaultClient.Do(req)
+	res, err := client.Do(req)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
-		os.Exit(1)
+		return ResultInfo{}, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		if r, ok := s.Auth.(Refresher); ok {
+			retry, err := r.Refresh(ctx, ep)
+			if err != nil {
+				res.Body.Close()
+				return ResultInfo{}, fmt.Errorf("refresh credentials: %w", err)
+			}
+			if retry {
+				res.Body.Close()
+				if req, sentHeaders, err = s.newRequest(ctx, ep, input, strings.NewReader(input.RawBody)); err != nil {
+					return ResultInfo{}, err
+				}
+				start = time.Now()
+				if res, err = client.Do(req); err != nil {
+					return ResultInfo{}, err
+				}
+			}
+		}
 	}
 	defer res.Body.Close()
 	elapsed := time.Since(start)
@@ -66,11 +123,12 @@ func Send(ep Endpoint, input InputResult) (ResultInfo, error) {
 		}
 	}
 
-	return ResultInfo{
+	result := ResultInfo{
 		Request: RequestInfo{
-			Method: ep.Method,
-			URL:    input.URL,
-			Body:   input.RawBody,
+			Method:  ep.Method,
+			URL:     input.URL,
+			Headers: sentHeaders,
+			Body:    input.RawBody,
 		},
 		Response: ResponseInfo{
 			StatusCode:  res.StatusCode,
@@ -81,5 +139,40 @@ func Send(ep Endpoint, input InputResult) (ResultInfo, error) {
 			RawBody:     bodyBytes,
 			JSONBody:    jsonBody,
 		},
-	}, nil
+	}
+	result.Response.Issues = CheckResponse(ep, result.Response)
+	return result, nil
+}
+
+// newRequest builds and authorizes the outgoing request. The returned
+// headers are captured before authorization so credentials never reach
+// the rendered output.
+func (s Sender) newRequest(ctx context.Context, ep Endpoint, input InputResult, body io.Reader) (*http.Request, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(ep.Method), input.URL, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range input.Headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	if body != nil && strings.TrimSpace(input.RawBody) != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", defaultContentType(ep))
+	}
+	sentHeaders := req.Header.Clone()
+
+	if s.Auth != nil {
+		if err := s.Auth.Authorize(req, ep); err != nil {
+			return nil, nil, fmt.Errorf("authorize: %w", err)
+		}
+	}
+	return req, sentHeaders, nil
+}
+
+// defaultContentType is the Content-Type of a body the user did not set
+// one for: the media type the operation documents the body with, or JSON.
+func defaultContentType(ep Endpoint) string {
+	if name := ep.Operation.RequestBody.JSONMediaTypeName(); name != "" {
+		return name
+	}
+	return "application/json"
 }
@@ -0,0 +1,142 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/atolix/clyst/spec"
+)
+
+// ContractIssue is a way a response departs from what its operation
+// documents.
+type ContractIssue struct {
+	// Kind is "status", "content-type", "header" or "body".
+	Kind string `json:"kind"`
+	// Location is the header name for header issues, and the JSON pointer
+	// of the offending value for body issues.
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i ContractIssue) String() string {
+	switch {
+	case i.Kind == "body" && i.Location == "":
+		return "body: " + i.Message
+	case i.Location != "":
+		return i.Kind + " " + i.Location + ": " + i.Message
+	}
+	return i.Message
+}
+
+// CheckResponse compares a response with the operation's documented
+// responses: the status code, the documented headers, the Content-Type,
+// and JSON bodies against their schema. Operations that document no
+// responses are not checked.
+func CheckResponse(ep Endpoint, resp ResponseInfo) []ContractIssue {
+	documented := ep.Operation.Responses
+	if len(documented) == 0 {
+		return nil
+	}
+	doc, ok := responseFor(documented, resp.StatusCode)
+	if !ok {
+		codes := slices.Sorted(maps.Keys(documented))
+		return []ContractIssue{{
+			Kind:    "status",
+			Message: fmt.Sprintf("status %d is not documented (documented: %s)", resp.StatusCode, strings.Join(codes, ", ")),
+		}}
+	}
+
+	var issues []ContractIssue
+	for _, name := range slices.Sorted(maps.Keys(doc.Headers)) {
+		// The spec says a Content-Type header definition is ignored.
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		h := doc.Headers[name]
+		value := resp.Headers.Get(name)
+		if value == "" {
+			if h.Required {
+				issues = append(issues, ContractIssue{Kind: "header", Location: http.CanonicalHeaderKey(name), Message: "is required but missing"})
+			}
+			continue
+		}
+		for _, v := range h.Schema.ValidateText(value, spec.DirectionResponse) {
+			issues = append(issues, ContractIssue{Kind: "header", Location: http.CanonicalHeaderKey(name), Message: v.String()})
+		}
+	}
+
+	if len(doc.Content) == 0 || len(resp.RawBody) == 0 {
+		return issues
+	}
+	mt, mediaType, ok := mediaTypeFor(doc.Content, resp.ContentType)
+	if !ok {
+		documentedTypes := strings.Join(slices.Sorted(maps.Keys(doc.Content)), ", ")
+		msg := fmt.Sprintf("%q is not documented (documented: %s)", resp.ContentType, documentedTypes)
+		if resp.ContentType == "" {
+			msg = "missing, documented: " + documentedTypes
+		}
+		return append(issues, ContractIssue{Kind: "content-type", Message: "Content-Type " + msg})
+	}
+	if mt.Schema == nil || !isJSONMediaType(mediaType) {
+		return issues
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(resp.RawBody)))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		return append(issues, ContractIssue{Kind: "body", Message: "is not valid JSON: " + err.Error()})
+	}
+	for _, v := range mt.Schema.Validate(body, spec.DirectionResponse) {
+		issues = append(issues, ContractIssue{Kind: "body", Location: v.Pointer, Message: v.Message})
+	}
+	return issues
+}
+
+// responseFor finds the documented response for a status code: the exact
+// code, then its range such as "4XX", then "default".
+func responseFor(responses map[string]spec.Response, code int) (spec.Response, bool) {
+	if r, ok := responses[strconv.Itoa(code)]; ok {
+		return r, true
+	}
+	for key, r := range responses {
+		if strings.EqualFold(key, fmt.Sprintf("%dXX", code/100)) {
+			return r, true
+		}
+	}
+	r, ok := responses["default"]
+	return r, ok
+}
+
+// mediaTypeFor finds the documented content for a Content-Type header: the
+// exact media type, then "type/*", then "*/*". It also returns the media
+// type of the header without parameters.
+func mediaTypeFor(content map[string]spec.MediaType, contentType string) (spec.MediaType, string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return spec.MediaType{}, "", false
+	}
+	byType := make(map[string]spec.MediaType, len(content))
+	for key, mt := range content {
+		if t, _, err := mime.ParseMediaType(key); err == nil {
+			byType[t] = mt
+		}
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, candidate := range []string{mediaType, major + "/*", "*/*"} {
+		if mt, ok := byType[candidate]; ok {
+			return mt, mediaType, true
+		}
+	}
+	return spec.MediaType{}, mediaType, false
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
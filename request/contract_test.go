@@ -0,0 +1,24 @@
+package request
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckResponseSkipsWriteOnly(t *testing.T) {
+	ep := mustOperation(t, accountOperation)
+	resp := ResponseInfo{
+		StatusCode:  201,
+		Headers:     http.Header{},
+		ContentType: "application/json",
+		RawBody:     []byte(`{"id":1,"name":"a"}`),
+	}
+	if issues := CheckResponse(ep, resp); len(issues) > 0 {
+		t.Fatalf("CheckResponse() = %v, want none", issues)
+	}
+	resp.RawBody = []byte(`{"name":"a"}`)
+	issues := CheckResponse(ep, resp)
+	if len(issues) != 1 || issues[0].String() != `body: missing required property "id"` {
+		t.Fatalf("CheckResponse() = %v, want the missing id", issues)
+	}
+}
@@ -0,0 +1,107 @@
+package request
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/atolix/clyst/spec"
+)
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Interpolate replaces {{name}} with the value of name in vars. Unknown
+// variables are left as written so the mistake shows up in the request.
+func Interpolate(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := variablePattern.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// EnvProvider wraps an InputProvider with an environment: values are
+// interpolated with Variables and Headers are sent unless the wrapped
+// provider sets the same header. A header named like one of Parameters,
+// the operation's, fills that parameter when it is left empty.
+type EnvProvider struct {
+	InputProvider
+	Variables  map[string]string
+	Headers    map[string]string
+	Parameters []spec.Parameter
+}
+
+func (e EnvProvider) GetServerVariable(name string, v spec.ServerVariable) string {
+	return Interpolate(e.InputProvider.GetServerVariable(name, v), e.Variables)
+}
+
+func (e EnvProvider) GetPathParam(p spec.Parameter) string {
+	return Interpolate(e.InputProvider.GetPathParam(p), e.Variables)
+}
+
+func (e EnvProvider) GetQueryParam(p spec.Parameter) string {
+	return Interpolate(e.InputProvider.GetQueryParam(p), e.Variables)
+}
+
+func (e EnvProvider) GetHeaderParam(p spec.Parameter) string {
+	v := e.InputProvider.GetHeaderParam(p)
+	if v == "" {
+		v = e.header(p.Name)
+	}
+	return Interpolate(v, e.Variables)
+}
+
+func (e EnvProvider) GetCookieParam(p spec.Parameter) string {
+	return Interpolate(e.InputProvider.GetCookieParam(p), e.Variables)
+}
+
+func (e EnvProvider) GetCustomHeaders() map[string]string {
+	out := map[string]string{}
+	for name, v := range e.Headers {
+		if !e.isParameter(name) {
+			out[name] = Interpolate(v, e.Variables)
+		}
+	}
+	for name, v := range e.InputProvider.GetCustomHeaders() {
+		for existing := range out {
+			if strings.EqualFold(existing, name) {
+				delete(out, existing)
+			}
+		}
+		out[name] = Interpolate(v, e.Variables)
+	}
+	return out
+}
+
+// header is the environment's value for the header name, "" when it sets
+// none.
+func (e EnvProvider) header(name string) string {
+	for n, v := range e.Headers {
+		if strings.EqualFold(n, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (e EnvProvider) isParameter(header string) bool {
+	for _, p := range e.Parameters {
+		if p.In == "header" && strings.EqualFold(p.Name, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e EnvProvider) GetRequestBody() string {
+	return Interpolate(e.InputProvider.GetRequestBody(), e.Variables)
+}
+
+func (e EnvProvider) Canceled() bool {
+	ca, ok := e.InputProvider.(CancelAware)
+	return ok && ca.Canceled()
+}
@@ -0,0 +1,63 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/atolix/clyst/spec"
+)
+
+const tenantOperation = `
+parameters:
+  - {name: X-Tenant, in: header, required: true, schema: {type: string}}
+responses:
+  "200": {description: ok}
+`
+
+func envFor(ep Endpoint, input StaticInput) EnvProvider {
+	return EnvProvider{
+		InputProvider: input,
+		Variables:     map[string]string{"tenant": "acme"},
+		Headers:       map[string]string{"x-tenant": "{{tenant}}", "X-Trace": "env"},
+		Parameters:    ep.Operation.Parameters,
+	}
+}
+
+func TestEnvHeaderDoesNotOverrideParameter(t *testing.T) {
+	ep := mustOperation(t, tenantOperation)
+	server := spec.Server{URL: "http://api.example"}
+	provider := envFor(ep, StaticInput{
+		Header:  map[string]string{"X-Tenant": "typed"},
+		Headers: map[string]string{"x-trace": "custom"},
+	})
+
+	in, _, err := AssembleInput(server, ep, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := in.Headers.Get("X-Tenant"); got != "typed" {
+		t.Errorf("X-Tenant = %q, want the typed parameter", got)
+	}
+	if got := in.Headers.Values("X-Trace"); len(got) != 1 || got[0] != "custom" {
+		t.Errorf("X-Trace = %q, want the custom header only", got)
+	}
+}
+
+func TestEnvHeaderFillsRequiredParameter(t *testing.T) {
+	ep := mustOperation(t, tenantOperation)
+	server := spec.Server{URL: "http://api.example"}
+	provider := envFor(ep, StaticInput{})
+
+	if errs := Validate(server, ep, provider); len(errs) > 0 {
+		t.Errorf("Validate() = %v, want the environment's header to count", errs)
+	}
+	in, _, err := AssembleInput(server, ep, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := in.Headers.Get("X-Tenant"); got != "acme" {
+		t.Errorf("X-Tenant = %q, want the environment's acme", got)
+	}
+	if got := in.Headers.Get("X-Trace"); got != "env" {
+		t.Errorf("X-Trace = %q, want the environment's header", got)
+	}
+}
@@ -0,0 +1,110 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	mediaTypeURLEncoded = "application/x-www-form-urlencoded"
+	mediaTypeMultipart  = "multipart/form-data"
+)
+
+// isFormMediaType reports whether bodies of contentType are encoded from
+// the JSON object the user enters, see encodeForm.
+func isFormMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == mediaTypeURLEncoded || mediaType == mediaTypeMultipart)
+}
+
+// encodeForm encodes body, a JSON object, as a form of contentType: each
+// property is a field, arrays give one field per item, and objects are
+// sent as JSON. In multipart forms a string starting with "@" attaches the
+// named file, as with curl. It returns the encoded body and the
+// Content-Type to send it with.
+func encodeForm(contentType, body string) (string, string, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return "", "", errors.New("a form body must be a JSON object")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == mediaTypeURLEncoded {
+		form := url.Values{}
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			for _, v := range formValues(fields[name]) {
+				form.Add(name, formText(v))
+			}
+		}
+		return form.Encode(), contentType, nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		for _, v := range formValues(fields[name]) {
+			if err := writeFormPart(w, name, v); err != nil {
+				return "", "", fmt.Errorf("form field %s: %w", name, err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	return buf.String(), w.FormDataContentType(), nil
+}
+
+func formValues(v any) []any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return t
+	}
+	return []any{v}
+}
+
+func formText(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		if t {
+			return "true"
+		}
+		return "false"
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+func writeFormPart(w *multipart.Writer, name string, v any) error {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, "@") {
+		return w.WriteField(name, formText(v))
+	}
+	data, err := os.ReadFile(s[1:])
+	if err != nil {
+		return err
+	}
+	part, err := w.CreateFormFile(name, filepath.Base(s[1:]))
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
@@ -1,7 +1,9 @@
 package request
 
 import (
+	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -10,13 +12,18 @@ import (
 
 type InputResult struct {
 	URL     string
+	Headers http.Header
 	RawBody string
 	Body    io.Reader
 }
 
 type InputProvider interface {
+	GetServerVariable(name string, v spec.ServerVariable) string
 	GetPathParam(p spec.Parameter) string
 	GetQueryParam(p spec.Parameter) string
+	GetHeaderParam(p spec.Parameter) string
+	GetCookieParam(p spec.Parameter) string
+	GetCustomHeaders() map[string]string
 	GetRequestBody() string
 }
 
@@ -24,11 +31,23 @@ type CancelAware interface {
 	Canceled() bool
 }
 
-func AssembleInput(baseURL string, ep Endpoint, provider InputProvider) (InputResult, bool, error) {
+func AssembleInput(server spec.Server, ep Endpoint, provider InputProvider) (InputResult, bool, error) {
 	if ca, ok := provider.(CancelAware); ok && ca.Canceled() {
 		return InputResult{}, true, nil
 	}
 
+	vars := make(map[string]string, len(server.Variables))
+	for name, v := range server.Variables {
+		vars[name] = provider.GetServerVariable(name, v)
+	}
+	if ca, ok := provider.(CancelAware); ok && ca.Canceled() {
+		return InputResult{}, true, nil
+	}
+	baseURL := strings.TrimRight(server.Expand(vars), "/")
+	if baseURL == "" {
+		return InputResult{}, false, fmt.Errorf("server URL is empty")
+	}
+
 	path := ep.Path
 
 	for _, p := range ep.Operation.Parameters {
@@ -38,7 +57,10 @@ func AssembleInput(baseURL string, ep Endpoint, provider InputProvider) (InputRe
 		}
 	}
 
-	u, _ := url.Parse(baseURL + path)
+	u, err := url.Parse(baseURL + path)
+	if err != nil {
+		return InputResult{}, false, err
+	}
 	q := u.Query()
 
 	for _, p := range ep.Operation.Parameters {
@@ -50,6 +72,29 @@ func AssembleInput(baseURL string, ep Endpoint, provider InputProvider) (InputRe
 		}
 	}
 
+	headers := http.Header{}
+	var cookies []string
+	for _, p := range ep.Operation.Parameters {
+		switch p.In {
+		case "header":
+			if v := provider.GetHeaderParam(p); v != "" {
+				headers.Set(p.Name, v)
+			}
+		case "cookie":
+			if v := provider.GetCookieParam(p); v != "" {
+				cookies = append(cookies, (&http.Cookie{Name: p.Name, Value: v}).String())
+			}
+		}
+	}
+	if len(cookies) > 0 {
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	for name, v := range provider.GetCustomHeaders() {
+		if strings.TrimSpace(name) != "" {
+			headers.Set(strings.TrimSpace(name), v)
+		}
+	}
+
 	if ca, ok := provider.(CancelAware); ok && ca.Canceled() {
 		return InputResult{}, true, nil
 	}
@@ -62,9 +107,24 @@ func AssembleInput(baseURL string, ep Endpoint, provider InputProvider) (InputRe
 			return InputResult{}, true, nil
 		}
 	}
+	if strings.TrimSpace(rawBody) != "" {
+		contentType := headers.Get("Content-Type")
+		if contentType == "" {
+			contentType = defaultContentType(ep)
+		}
+		if isFormMediaType(contentType) {
+			body, formType, err := encodeForm(contentType, rawBody)
+			if err != nil {
+				return InputResult{}, false, err
+			}
+			rawBody = body
+			headers.Set("Content-Type", formType)
+		}
+	}
 
 	return InputResult{
 		URL:     u.String(),
+		Headers: headers,
 		RawBody: rawBody,
 		Body:    strings.NewReader(rawBody),
 	}, false, nil
@@ -4,14 +4,54 @@ import (
 	"github.com/atolix/clyst/params"
 )
 
-func SavePreset(dir string, ep Endpoint, provider InputProvider) error {
-	store, err := params.Load(dir)
+// PresetNamer is implemented by providers that let the user name the
+// preset being recorded.
+type PresetNamer interface {
+	PresetName() string
+}
+
+// PresetEditor is implemented by providers that were opened from a saved
+// preset for editing; SavePreset then updates that preset, found by its
+// ID, in place.
+type PresetEditor interface {
+	EditingPreset() (id string, editing bool)
+}
+
+// PresetScoper is implemented by providers that let the user keep a preset
+// local or mark fields as secret (see params.SecretRef).
+type PresetScoper interface {
+	PresetLocal() bool
+	PresetSecrets() []string
+}
+
+func SavePreset(dir, specID string, ep Endpoint, provider InputProvider) error {
+	store, err := params.Load(dir, specID)
 	if err != nil {
 		return err
 	}
 
+	preset := PresetValues(ep, provider)
+	if n, ok := provider.(PresetNamer); ok {
+		preset.Name = n.PresetName()
+	}
+	if sc, ok := provider.(PresetScoper); ok {
+		preset.Local = sc.PresetLocal()
+	}
+	if e, ok := provider.(PresetEditor); ok {
+		if id, editing := e.EditingPreset(); editing {
+			return store.UpdatePreset(ep.Method, ep.Path, id, preset)
+		}
+	}
+	return store.AppendPreset(ep.Method, ep.Path, preset)
+}
+
+// PresetValues captures the values the provider holds for ep, including
+// which of them are secret, in the form presets store them.
+func PresetValues(ep Endpoint, provider InputProvider) params.StoredParams {
 	pathVals := map[string]string{}
 	queryVals := map[string]string{}
+	headerVals := map[string]string{}
+	cookieVals := map[string]string{}
 	for _, p := range ep.Operation.Parameters {
 		switch p.In {
 		case "path":
@@ -20,6 +60,14 @@ func SavePreset(dir string, ep Endpoint, provider InputProvider) error {
 			if v := provider.GetQueryParam(p); v != "" {
 				queryVals[p.Name] = v
 			}
+		case "header":
+			if v := provider.GetHeaderParam(p); v != "" {
+				headerVals[p.Name] = v
+			}
+		case "cookie":
+			if v := provider.GetCookieParam(p); v != "" {
+				cookieVals[p.Name] = v
+			}
 		}
 	}
 
@@ -28,9 +76,16 @@ func SavePreset(dir string, ep Endpoint, provider InputProvider) error {
 		body = provider.GetRequestBody()
 	}
 
-	return store.AppendPreset(ep.Method, ep.Path, params.StoredParams{
-		Path:  pathVals,
-		Query: queryVals,
-		Body:  body,
-	})
+	values := params.StoredParams{
+		Path:          pathVals,
+		Query:         queryVals,
+		Header:        headerVals,
+		Cookie:        cookieVals,
+		Body:          body,
+		CustomHeaders: provider.GetCustomHeaders(),
+	}
+	if sc, ok := provider.(PresetScoper); ok {
+		values.Secrets = sc.PresetSecrets()
+	}
+	return values
 }
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"strings"
+
+	"github.com/atolix/clyst/params"
+	"github.com/atolix/clyst/spec"
+)
+
+// StaticInput is an InputProvider over fixed values, used when there is no
+// form to ask, e.g. on the command line. Missing values are empty, so server
+// variables fall back to their defaults.
+type StaticInput struct {
+	ServerVariables map[string]string
+	Path            map[string]string
+	Query           map[string]string
+	Header          map[string]string
+	Cookie          map[string]string
+	// Headers are sent as-is and override parameters of the same name.
+	Headers map[string]string
+	Body    string
+}
+
+// PresetInput returns the values of a saved preset or history entry.
+func PresetInput(p params.StoredParams) StaticInput {
+	return StaticInput{
+		Path:    p.Path,
+		Query:   p.Query,
+		Header:  p.Header,
+		Cookie:  p.Cookie,
+		Headers: p.CustomHeaders,
+		Body:    p.Body,
+	}
+}
+
+func (s StaticInput) GetServerVariable(name string, _ spec.ServerVariable) string {
+	return s.ServerVariables[name]
+}
+
+func (s StaticInput) GetPathParam(p spec.Parameter) string   { return s.Path[p.Name] }
+func (s StaticInput) GetQueryParam(p spec.Parameter) string  { return s.Query[p.Name] }
+func (s StaticInput) GetCookieParam(p spec.Parameter) string { return s.Cookie[p.Name] }
+
+// GetHeaderParam matches names case-insensitively, like HTTP does.
+func (s StaticInput) GetHeaderParam(p spec.Parameter) string {
+	if v, ok := s.Header[p.Name]; ok {
+		return v
+	}
+	for name, v := range s.Header {
+		if strings.EqualFold(name, p.Name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (s StaticInput) GetCustomHeaders() map[string]string { return s.Headers }
+func (s StaticInput) GetRequestBody() string              { return s.Body }
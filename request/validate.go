@@ -0,0 +1,136 @@
+package request
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/atolix/clyst/spec"
+)
+
+// FieldError is an input value that does not fit the spec. Kind is where
+// the value goes: "server", "path", "query", "header", "cookie" or "body".
+// Name is the server variable or parameter; for the body it is the JSON
+// pointer of the offending value, "" for the body as a whole.
+type FieldError struct {
+	Kind    string
+	Name    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Name == "" {
+		return e.Kind + ": " + e.Message
+	}
+	return e.Kind + " " + e.Name + ": " + e.Message
+}
+
+// Validate checks the values before they are sent: required parameters,
+// parameter schemas, server variable enums, and a JSON or form body
+// against its schema. Values still holding an environment placeholder
+// ({{name}}) are only checked for presence, as the environment fills them
+// in later.
+func Validate(server spec.Server, ep Endpoint, provider InputProvider) []FieldError {
+	var errs []FieldError
+	for _, name := range server.VariableNames() {
+		v := server.Variables[name]
+		value := provider.GetServerVariable(name, v)
+		if value != "" && len(v.Enum) > 0 && !hasPlaceholder(value) && !slices.Contains(v.Enum, value) {
+			errs = append(errs, FieldError{Kind: "server", Name: name, Message: "must be one of " + strings.Join(v.Enum, ", ")})
+		}
+	}
+
+	for _, p := range ep.Operation.Parameters {
+		var value string
+		switch p.In {
+		case "path":
+			value = provider.GetPathParam(p)
+		case "query":
+			value = provider.GetQueryParam(p)
+		case "header":
+			value = provider.GetHeaderParam(p)
+		case "cookie":
+			value = provider.GetCookieParam(p)
+		default:
+			continue
+		}
+		switch {
+		case value == "":
+			if p.Required || p.In == "path" {
+				errs = append(errs, FieldError{Kind: p.In, Name: p.Name, Message: "is required"})
+			}
+		case hasPlaceholder(value):
+		default:
+			for _, v := range p.Schema.ValidateText(value, spec.DirectionRequest) {
+				errs = append(errs, FieldError{Kind: p.In, Name: p.Name, Message: v.String()})
+			}
+		}
+	}
+
+	if body := ep.Operation.RequestBody; body != nil {
+		errs = append(errs, validateBody(body, bodyContentType(ep, provider), provider.GetRequestBody())...)
+	}
+	return errs
+}
+
+// validateBody checks a JSON or form body, entered as a JSON object,
+// against the schema documented for contentType. Other bodies, such as
+// text or XML, are only checked for presence.
+func validateBody(body *spec.RequestBody, contentType, text string) []FieldError {
+	switch {
+	case strings.TrimSpace(text) == "":
+		if body.Required {
+			return []FieldError{{Kind: "body", Message: "is required"}}
+		}
+		return nil
+	case hasPlaceholder(text):
+		return nil
+	}
+
+	mt, mediaType, documented := mediaTypeFor(body.Content, contentType)
+	if !isJSONMediaType(mediaType) && !isFormMediaType(mediaType) {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []FieldError{{Kind: "body", Message: "is not valid JSON: " + err.Error()}}
+	}
+	if dec.More() {
+		return []FieldError{{Kind: "body", Message: "is not valid JSON: unexpected data after the value"}}
+	}
+
+	if !documented {
+		return nil
+	}
+	var errs []FieldError
+	for _, violation := range mt.Schema.Validate(v, spec.DirectionRequest) {
+		errs = append(errs, FieldError{Kind: "body", Name: violation.Pointer, Message: violation.Message})
+	}
+	return errs
+}
+
+// bodyContentType is the Content-Type the body will be sent with, as
+// AssembleInput picks it: a custom header, then a header parameter, then
+// the documented media type.
+func bodyContentType(ep Endpoint, provider InputProvider) string {
+	for name, v := range provider.GetCustomHeaders() {
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Type") && v != "" {
+			return v
+		}
+	}
+	for _, p := range ep.Operation.Parameters {
+		if p.In == "header" && strings.EqualFold(p.Name, "Content-Type") {
+			if v := provider.GetHeaderParam(p); v != "" {
+				return v
+			}
+		}
+	}
+	return defaultContentType(ep)
+}
+
+func hasPlaceholder(s string) bool {
+	return strings.Contains(s, "{{")
+}
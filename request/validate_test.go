@@ -0,0 +1,74 @@
+package request
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/atolix/clyst/spec"
+)
+
+func mustOperation(t *testing.T, src string) Endpoint {
+	t.Helper()
+	var op spec.Operation
+	if err := yaml.Unmarshal([]byte(src), &op); err != nil {
+		t.Fatalf("parsing operation: %v", err)
+	}
+	return Endpoint{Method: "POST", Path: "/accounts", Operation: op}
+}
+
+const accountOperation = `
+requestBody:
+  required: true
+  content:
+    application/json:
+      schema: &account
+        type: object
+        required: [id, name, password]
+        properties:
+          id: {type: integer, readOnly: true}
+          name: {type: string}
+          password: {type: string, writeOnly: true}
+responses:
+  "201":
+    description: created
+    content:
+      application/json:
+        schema: *account
+`
+
+func TestValidateBodySkipsReadOnly(t *testing.T) {
+	ep := mustOperation(t, accountOperation)
+	input := StaticInput{Body: `{"name":"a","password":"p"}`}
+	if errs := Validate(spec.Server{}, ep, input); len(errs) > 0 {
+		t.Fatalf("Validate() = %v, want none", errs)
+	}
+	input.Body = `{"name":"a"}`
+	errs := Validate(spec.Server{}, ep, input)
+	if len(errs) != 1 || errs[0].Error() != `body: missing required property "password"` {
+		t.Fatalf("Validate() = %v, want the missing password", errs)
+	}
+}
+
+func TestValidateBodySkipsNonJSON(t *testing.T) {
+	ep := mustOperation(t, `
+requestBody:
+  required: true
+  content:
+    text/plain:
+      schema: {type: string}
+`)
+	if errs := Validate(spec.Server{}, ep, StaticInput{Body: "plain text"}); len(errs) > 0 {
+		t.Fatalf("Validate() = %v, want none for a text body", errs)
+	}
+
+	ep = mustOperation(t, accountOperation)
+	input := StaticInput{Body: "<account/>", Headers: map[string]string{"content-type": "application/xml"}}
+	if errs := Validate(spec.Server{}, ep, input); len(errs) > 0 {
+		t.Fatalf("Validate() = %v, want none for an XML body", errs)
+	}
+	input.Headers = nil
+	if errs := Validate(spec.Server{}, ep, input); len(errs) != 1 || errs[0].Kind != "body" {
+		t.Fatalf("Validate() = %v, want the body rejected as JSON", errs)
+	}
+}
@@ -1,183 +1,306 @@
 package spec
 
 import (
-	"errors"
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"os"
-	"path"
+	"net/http"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type OpenApiSpec struct {
+	// Source is the file path, URL or "-" the spec was loaded from.
+	Source string `yaml:"-"`
+	// Stale is set when a remote spec could not be fetched and the last
+	// cached copy was used instead.
+	Stale   bool                            `yaml:"-"`
+	OpenAPI string                          `yaml:"openapi"`
+	Info    Info                            `yaml:"info"`
 	BaseURL string                          `yaml:"baseUrl"`
+	Servers []Server                        `yaml:"servers"`
 	Paths   map[string]map[string]Operation `yaml:"paths"`
+	// Webhooks (OpenAPI 3.1) are requests the API sends to its consumers.
+	// They can be browsed but not sent.
+	Webhooks        map[string]map[string]Operation `yaml:"webhooks"`
+	SecuritySchemes map[string]SecurityScheme       `yaml:"securitySchemes"`
+}
+
+type Server struct {
+	URL         string                    `yaml:"url"`
+	Description string                    `yaml:"description"`
+	Variables   map[string]ServerVariable `yaml:"variables"`
+}
+
+type ServerVariable struct {
+	Enum        []string `yaml:"enum"`
+	Default     string   `yaml:"default"`
+	Description string   `yaml:"description"`
 }
 
 type Parameter struct {
-	Name     string `yaml:"name"`
-	In       string `yaml:"in"`
-	Required bool   `yaml:"required"`
-	Schema   struct {
-		Type string `yaml:"type"`
-	} `yaml:"schema"`
+	Name        string             `yaml:"name"`
+	In          string             `yaml:"in"`
+	Description string             `yaml:"description"`
+	Required    bool               `yaml:"required"`
+	Deprecated  bool               `yaml:"deprecated"`
+	Schema      Schema             `yaml:"schema"`
+	Example     any                `yaml:"example"`
+	Examples    map[string]Example `yaml:"examples"`
+}
+
+type Example struct {
+	Summary string `yaml:"summary"`
+	Value   any    `yaml:"value"`
+}
+
+type MediaType struct {
+	Schema   *Schema            `yaml:"schema"`
+	Example  any                `yaml:"example"`
+	Examples map[string]Example `yaml:"examples"`
 }
 
 type RequestBody struct {
-	Content map[string]struct {
-		Schema map[string]any `yaml:"schema"`
-	} `yaml:"content"`
+	Description string               `yaml:"description"`
+	Required    bool                 `yaml:"required"`
+	Content     map[string]MediaType `yaml:"content"`
 }
 
+// Response is a documented response. Content is keyed by media type, which
+// may be a range such as "text/*".
 type Response struct {
+	Description string               `yaml:"description"`
+	Headers     map[string]Header    `yaml:"headers"`
+	Content     map[string]MediaType `yaml:"content"`
+}
+
+type Header struct {
 	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	Schema      Schema `yaml:"schema"`
 }
 
 type Operation struct {
 	Summary     string              `yaml:"summary"`
+	Servers     []Server            `yaml:"servers"`
 	Parameters  []Parameter         `yaml:"parameters"`
 	RequestBody *RequestBody        `yaml:"requestBody"`
 	Responses   map[string]Response `yaml:"responses"`
+	// Security is the effective requirement list: the operation's own, or
+	// the document default when the operation does not override it.
+	Security []SecurityRequirement `yaml:"security"`
 }
 
 type componentsRaw struct {
-	Parameters    map[string]Parameter   `yaml:"parameters"`
-	RequestBodies map[string]RequestBody `yaml:"requestBodies"`
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
 }
 
 type openAPISpecRaw struct {
-	BaseURL    string                             `yaml:"base_url"`
-	Paths      map[string]map[string]operationRaw `yaml:"paths"`
-	Components componentsRaw                      `yaml:"components"`
+	OpenAPI      string                 `yaml:"openapi"`
+	Info         Info                   `yaml:"info"`
+	BaseURL      string                 `yaml:"base_url"`
+	BaseURLCamel string                 `yaml:"baseUrl"`
+	Servers      []Server               `yaml:"servers"`
+	Paths        map[string]pathItemRaw `yaml:"paths"`
+	Webhooks     map[string]pathItemRaw `yaml:"webhooks"`
+	Components   componentsRaw          `yaml:"components"`
+	Security     []SecurityRequirement  `yaml:"security"`
 }
 
-type parameterOrRef struct {
-	Ref      string `yaml:"$ref"`
-	Name     string `yaml:"name"`
-	In       string `yaml:"in"`
-	Required bool   `yaml:"required"`
-	Schema   struct {
-		Type string `yaml:"type"`
-	} `yaml:"schema"`
+// httpMethods lists the path item keys that describe operations.
+var httpMethods = map[string]struct{}{
+	"get": {}, "put": {}, "post": {}, "delete": {},
+	"options": {}, "head": {}, "patch": {}, "trace": {},
 }
 
-type requestBodyOrRef struct {
-	Ref     string `yaml:"$ref"`
-	Content map[string]struct {
-		Schema map[string]any `yaml:"schema"`
-	} `yaml:"content"`
+type pathItemRaw struct {
+	Servers    []Server
+	Parameters []Parameter
+	Operations map[string]Operation
 }
 
-type operationRaw struct {
-	Summary     string              `yaml:"summary"`
-	Parameters  []parameterOrRef    `yaml:"parameters"`
-	RequestBody *requestBodyOrRef   `yaml:"requestBody"`
-	Responses   map[string]Response `yaml:"responses"`
+func (p *pathItemRaw) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("path item must be a mapping (line %d)", node.Line)
+	}
+
+	p.Operations = map[string]Operation{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, val := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case key == "servers":
+			if err := val.Decode(&p.Servers); err != nil {
+				return err
+			}
+		case key == "parameters":
+			if err := val.Decode(&p.Parameters); err != nil {
+				return err
+			}
+		default:
+			if _, ok := httpMethods[strings.ToLower(key)]; !ok {
+				continue
+			}
+			var op Operation
+			if err := val.Decode(&op); err != nil {
+				return err
+			}
+			p.Operations[key] = op
+		}
+	}
+
+	return nil
+}
+
+// LoadOptions controls how references outside the spec file are resolved.
+type LoadOptions struct {
+	// AllowRemoteRefs enables fetching `http(s)://` references.
+	AllowRemoteRefs bool
+	// HTTPClient fetches remote references. Tests can serve a fixture
+	// directory with http.NewFileTransport. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// CacheDir keeps copies of remote documents for offline use.
+	// Caching is disabled when empty.
+	CacheDir string
+}
+
+func (o LoadOptions) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
 }
 
 func Load(filename string) (*OpenApiSpec, error) {
-	data, err := os.ReadFile(filename)
+	return LoadWithOptions(filename, LoadOptions{})
+}
+
+func LoadWithOptions(filename string, opts LoadOptions) (*OpenApiSpec, error) {
+	abs, err := filepath.Abs(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var raw openAPISpecRaw
-	if err := yaml.Unmarshal(data, &raw); err != nil {
+	resolver := newRefResolver(opts)
+	doc, err := resolver.load(abs, false)
+	if err != nil {
 		return nil, err
 	}
 
-	resolved := &OpenApiSpec{
-		BaseURL: raw.BaseURL,
-		Paths:   make(map[string]map[string]Operation, len(raw.Paths)),
-	}
-
-	for p, methods := range raw.Paths {
-		outMethods := make(map[string]Operation, len(methods))
-		for method, op := range methods {
-			rop, err := resolveOperation(op, raw.Components)
-			if err != nil {
-				return nil, fmt.Errorf("resolve %s %s: %w", strings.ToUpper(method), p, err)
-			}
-			outMethods[method] = rop
-		}
-		resolved.Paths[p] = outMethods
+	out, err := buildSpec(resolver, doc)
+	if err != nil {
+		return nil, err
 	}
-
-	return resolved, nil
+	out.Source = filename
+	return out, nil
 }
 
-func resolveOperation(in operationRaw, comps componentsRaw) (Operation, error) {
-	var out Operation
-	out.Summary = in.Summary
-	out.Responses = in.Responses
+// buildSpec resolves every reference reachable from doc and decodes the
+// result into the typed model.
+func buildSpec(resolver *refResolver, doc *document) (*OpenApiSpec, error) {
+	resolvedTree, err := resolver.resolve(doc)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, pr := range in.Parameters {
-		if strings.TrimSpace(pr.Ref) != "" {
-			name, kind, err := parseLocalRef(pr.Ref)
-			if err != nil {
-				return Operation{}, err
-			}
+	var node yaml.Node
+	if err := node.Encode(resolvedTree); err != nil {
+		return nil, err
+	}
+	var raw openAPISpecRaw
+	if err := node.Decode(&raw); err != nil {
+		return nil, err
+	}
 
-			if kind != "parameters" {
-				return Operation{}, fmt.Errorf("unsupported $ref kind for parameter: %s", kind)
-			}
+	baseURL := raw.BaseURL
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = raw.BaseURLCamel
+	}
 
-			param, ok := comps.Parameters[name]
-			if !ok {
-				return Operation{}, fmt.Errorf("unresolved parameter ref: %s", pr.Ref)
-			}
+	servers := raw.Servers
+	if len(servers) == 0 && strings.TrimSpace(baseURL) != "" {
+		servers = []Server{{URL: baseURL}}
+	}
 
-			out.Parameters = append(out.Parameters, param)
-			continue
-		}
-		out.Parameters = append(out.Parameters, Parameter{
-			Name:     pr.Name,
-			In:       pr.In,
-			Required: pr.Required,
-			Schema:   pr.Schema,
-		})
-	}
-
-	if in.RequestBody != nil {
-		rb := in.RequestBody
-		if strings.TrimSpace(rb.Ref) != "" {
-			name, kind, err := parseLocalRef(rb.Ref)
-			if err != nil {
-				return Operation{}, err
-			}
+	resolved := &OpenApiSpec{
+		OpenAPI:         raw.OpenAPI,
+		Info:            raw.Info,
+		BaseURL:         baseURL,
+		Servers:         servers,
+		Paths:           resolvePathItems(raw.Paths, servers, raw.Security),
+		Webhooks:        resolvePathItems(raw.Webhooks, nil, nil),
+		SecuritySchemes: raw.Components.SecuritySchemes,
+	}
 
-			if kind != "requestBodies" {
-				return Operation{}, fmt.Errorf("unsupported $ref kind for requestBody: %s", kind)
+	return resolved, nil
+}
 
+func resolvePathItems(items map[string]pathItemRaw, servers []Server, security []SecurityRequirement) map[string]map[string]Operation {
+	out := make(map[string]map[string]Operation, len(items))
+	for p, item := range items {
+		outMethods := make(map[string]Operation, len(item.Operations))
+		for method, op := range item.Operations {
+			op.Parameters = mergeParameters(item.Parameters, op.Parameters)
+			op.Servers = effectiveServers(servers, item.Servers, op.Servers)
+			if op.Security == nil {
+				op.Security = security
 			}
-			body, ok := comps.RequestBodies[name]
-			if !ok {
-				return Operation{}, fmt.Errorf("unresolved requestBody ref: %s", rb.Ref)
-			}
-
-			b := body
-			out.RequestBody = &b
-		} else {
-			out.RequestBody = &RequestBody{Content: rb.Content}
+			outMethods[method] = op
 		}
+		out[p] = outMethods
 	}
+	return out
+}
 
-	return out, nil
+// parseDocument decodes a JSON or YAML document into a generic tree.
+func parseDocument(data []byte) (any, error) {
+	var tree any
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		if err := json.Unmarshal(trimmed, &tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
 }
 
-func parseLocalRef(ref string) (name string, kind string, err error) {
-	if !strings.HasPrefix(ref, "#/") {
-		return "", "", errors.New("only local $ref supported (must start with #/)")
+// effectiveServers applies the OpenAPI override rules: operation-level
+// servers win over path-level ones, which win over the document root.
+func effectiveServers(root, pathLevel, opLevel []Server) []Server {
+	switch {
+	case len(opLevel) > 0:
+		return opLevel
+	case len(pathLevel) > 0:
+		return pathLevel
+	default:
+		return root
 	}
+}
 
-	parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
-	if len(parts) < 3 || parts[0] != "components" {
-		return "", "", fmt.Errorf("invalid $ref format: %s", ref)
+// mergeParameters combines path item parameters with operation parameters.
+// Operation parameters override path-level ones with the same name and location.
+func mergeParameters(pathLevel, opLevel []Parameter) []Parameter {
+	if len(pathLevel) == 0 {
+		return opLevel
 	}
 
-	kind = parts[1]
-	name = path.Clean(strings.Join(parts[2:], "/"))
+	out := make([]Parameter, 0, len(pathLevel)+len(opLevel))
+	for _, pp := range pathLevel {
+		overridden := false
+		for _, op := range opLevel {
+			if pp.Name == op.Name && pp.In == op.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			out = append(out, pp)
+		}
+	}
 
-	return name, kind, nil
+	return append(out, opLevel...)
 }
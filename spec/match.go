@@ -0,0 +1,58 @@
+package spec
+
+import "strings"
+
+// FindOperation looks up the operation for method and path. path may be the
+// template as written in the spec ("/users/{id}") or a concrete path
+// ("/users/3"); for a concrete path the returned map holds the values of the
+// template's path parameters. An exact template match wins over a pattern.
+func (s *OpenApiSpec) FindOperation(method, path string) (string, Operation, map[string]string, bool) {
+	method = strings.ToLower(method)
+	if op, ok := s.Paths[path][method]; ok {
+		return path, op, nil, true
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var (
+		best      string
+		bestVars  map[string]string
+		bestFixed = -1
+	)
+	for template, methods := range s.Paths {
+		if _, ok := methods[method]; !ok {
+			continue
+		}
+		vars, fixed, ok := matchTemplate(template, segments)
+		// Prefer the most literal segments, e.g. /users/me over /users/{id}.
+		if ok && (fixed > bestFixed || fixed == bestFixed && template < best) {
+			best, bestVars, bestFixed = template, vars, fixed
+		}
+	}
+	if bestFixed < 0 {
+		return "", Operation{}, nil, false
+	}
+	return best, s.Paths[best][method], bestVars, true
+}
+
+func matchTemplate(template string, segments []string) (map[string]string, int, bool) {
+	parts := strings.Split(strings.Trim(template, "/"), "/")
+	if len(parts) != len(segments) {
+		return nil, 0, false
+	}
+	vars := map[string]string{}
+	fixed := 0
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			vars[part[1:len(part)-1]] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, 0, false
+		}
+		fixed++
+	}
+	return vars, fixed, true
+}
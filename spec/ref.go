@@ -0,0 +1,321 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// refResolver expands `$ref` objects in a generic document tree using JSON
+// pointers. A reference that points back into one of its own ancestors is a
+// cycle; it is left in place as a `$ref` object so the result stays finite.
+//
+// References may point into other files relative to the referring document
+// (`schemas/common.yml#/User`). Loaded documents are cached by absolute path
+// or URL; `http(s)://` documents are only fetched when remote refs are allowed.
+type refResolver struct {
+	opts  LoadOptions
+	docs  map[string]*document
+	memo  map[string]any
+	stack []string
+}
+
+type document struct {
+	key  string // absolute file path or URL
+	root any
+	// swagger is set for Swagger 2.0 documents and the files they refer
+	// to, whose refs are rewritten to the OpenAPI 3 layout.
+	swagger bool
+	// swaggerParams are the Swagger 2.0 `parameters` as written, so a
+	// document referring to one can tell body and form parameters apart.
+	swaggerParams map[string]any
+}
+
+func newRefResolver(opts LoadOptions) *refResolver {
+	return &refResolver{opts: opts, docs: map[string]*document{}, memo: map[string]any{}}
+}
+
+// addDocument registers an already parsed document under key. Swagger 2.0
+// documents are converted to the OpenAPI 3 layout first, and so are files
+// a Swagger 2.0 document refers to (fromSwagger), see
+// convertSwaggerFragment.
+func (r *refResolver) addDocument(key string, root any, fromSwagger bool) *document {
+	root = normalizeTree(root)
+	m, isMap := root.(map[string]any)
+	swagger2 := isSwagger2(root)
+	doc := &document{key: key, root: root, swagger: swagger2 || fromSwagger && isMap && m["openapi"] == nil}
+	// Registered before converting, so files that refer to each other's
+	// parameters find them.
+	r.docs[key] = doc
+	if !doc.swagger {
+		return doc
+	}
+	doc.swaggerParams, _ = m["parameters"].(map[string]any)
+	external := func(location string) map[string]any {
+		return r.swaggerParameters(location, key)
+	}
+	if swagger2 {
+		doc.root = convertSwagger2(m, external)
+	} else {
+		doc.root = convertSwaggerFragment(m)
+	}
+	return doc
+}
+
+// swaggerParameters returns the Swagger 2.0 parameters of the file at
+// location, relative to base; nil when it cannot be loaded, in which case
+// resolving the ref reports the problem.
+func (r *refResolver) swaggerParameters(location, base string) map[string]any {
+	key, err := r.locate(location, base)
+	if err != nil {
+		return nil
+	}
+	doc, err := r.load(key, true)
+	if err != nil {
+		return nil
+	}
+	return doc.swaggerParams
+}
+
+// resolve returns a copy of the document root with every reachable `$ref` expanded.
+func (r *refResolver) resolve(doc *document) (any, error) {
+	out, _, err := r.walk(doc.root, doc)
+	return out, err
+}
+
+// walk reports whether a cycle was cut somewhere below node, in which case
+// the result depends on the current stack and must not be memoized.
+func (r *refResolver) walk(node any, doc *document) (any, bool, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			return r.resolveRef(ref, v, doc)
+		}
+		out := make(map[string]any, len(v))
+		cyclic := false
+		for k, child := range v {
+			rc, c, err := r.walk(child, doc)
+			if err != nil {
+				return nil, false, err
+			}
+			cyclic = cyclic || c
+			out[k] = rc
+		}
+		return out, cyclic, nil
+	case []any:
+		out := make([]any, len(v))
+		cyclic := false
+		for i, child := range v {
+			rc, c, err := r.walk(child, doc)
+			if err != nil {
+				return nil, false, err
+			}
+			cyclic = cyclic || c
+			out[i] = rc
+		}
+		return out, cyclic, nil
+	default:
+		return node, false, nil
+	}
+}
+
+func (r *refResolver) resolveRef(ref string, holder map[string]any, from *document) (any, bool, error) {
+	location, fragment, _ := strings.Cut(ref, "#")
+	target := from
+	if location != "" {
+		key, err := r.locate(location, from.key)
+		if err != nil {
+			return nil, false, fmt.Errorf("resolve $ref %s: %w", ref, err)
+		}
+		if target, err = r.load(key, from.swagger); err != nil {
+			return nil, false, fmt.Errorf("resolve $ref %s: %w", ref, err)
+		}
+	}
+
+	abs := target.key + "#" + fragment
+	for _, s := range r.stack {
+		if s == abs {
+			return holder, true, nil
+		}
+	}
+
+	resolved, ok := r.memo[abs]
+	cyclic := false
+	if !ok {
+		node, err := lookupPointer(target.root, fragment)
+		if err != nil {
+			return nil, false, fmt.Errorf("unresolved $ref %s: %w", ref, err)
+		}
+
+		r.stack = append(r.stack, abs)
+		resolved, cyclic, err = r.walk(node, target)
+		r.stack = r.stack[:len(r.stack)-1]
+		if err != nil {
+			return nil, false, err
+		}
+		if !cyclic {
+			r.memo[abs] = resolved
+		}
+	}
+
+	return mergeRefSiblings(resolved, holder), cyclic, nil
+}
+
+// locate turns the file part of a reference into a cache key, relative to
+// the document that contains the reference.
+func (r *refResolver) locate(location, base string) (string, error) {
+	if isRemote(location) {
+		return location, nil
+	}
+	if isRemote(base) {
+		bu, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+		lu, err := url.Parse(location)
+		if err != nil {
+			return "", err
+		}
+		return bu.ResolveReference(lu).String(), nil
+	}
+	if filepath.IsAbs(location) {
+		return filepath.Clean(location), nil
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(location)), nil
+}
+
+// load reads the document at key; fromSwagger is passed on to addDocument.
+func (r *refResolver) load(key string, fromSwagger bool) (*document, error) {
+	if doc, ok := r.docs[key]; ok {
+		return doc, nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if isRemote(key) {
+		if !r.opts.AllowRemoteRefs {
+			return nil, errors.New("remote $ref is disabled (set allow_remote_refs)")
+		}
+		data, _, err = fetchCached(r.opts.httpClient(), key, r.opts.CacheDir)
+	} else {
+		data, err = os.ReadFile(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", key, err)
+	}
+	return r.addDocument(key, root, fromSwagger), nil
+}
+
+func isRemote(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func fetchRemote(client *http.Client, rawURL string) ([]byte, error) {
+	res, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// mergeRefSiblings keeps keys written next to `$ref` (allowed since
+// OpenAPI 3.1, e.g. a local description) on top of the resolved object.
+func mergeRefSiblings(resolved any, holder map[string]any) any {
+	if len(holder) <= 1 {
+		return resolved
+	}
+	m, ok := resolved.(map[string]any)
+	if !ok {
+		return resolved
+	}
+	out := make(map[string]any, len(m)+len(holder))
+	for k, v := range m {
+		out[k] = v
+	}
+	for k, v := range holder {
+		if k != "$ref" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// lookupPointer evaluates an RFC 6901 JSON pointer (the part after `#`).
+func lookupPointer(root any, pointer string) (any, error) {
+	if pointer == "" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	cur := root
+	for _, raw := range strings.Split(pointer[1:], "/") {
+		tok, err := url.PathUnescape(raw)
+		if err != nil {
+			return nil, err
+		}
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", tok)
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("index %q out of range", tok)
+			}
+			cur = v[i]
+		default:
+			return nil, errors.New("pointer traverses a scalar value")
+		}
+	}
+
+	return cur, nil
+}
+
+// normalizeTree converts the map[any]any values that YAML produces for
+// non-string keys (e.g. unquoted response codes) into map[string]any.
+func normalizeTree(node any) any {
+	switch v := node.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = normalizeTree(child)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[fmt.Sprint(k)] = normalizeTree(child)
+		}
+		return out
+	case []any:
+		for i, child := range v {
+			v[i] = normalizeTree(child)
+		}
+		return v
+	default:
+		return node
+	}
+}
@@ -0,0 +1,126 @@
+package spec
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func responseSchema(t *testing.T, doc *OpenApiSpec, method, path, status string) *Schema {
+	t.Helper()
+	op, ok := doc.Paths[path][method]
+	if !ok {
+		t.Fatalf("no operation %s %s", method, path)
+	}
+	s := op.Responses[status].Content["application/json"].Schema
+	if s == nil {
+		t.Fatalf("%s %s: no %s response schema", method, path, status)
+	}
+	return s
+}
+
+func TestLoadResolvesRelativeFileRefs(t *testing.T) {
+	doc, err := Load("testdata/refs/api.yml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	user := responseSchema(t, doc, "get", "/users", "200")
+	if user.Ref != "" || user.Properties["id"] == nil {
+		t.Fatalf("user schema = %+v, want the resolved User", user)
+	}
+	// common.yml is relative to schemas/user.yml, not to the spec.
+	city := user.Properties["address"].Properties["city"]
+	if city == nil || city.Types[0] != "string" {
+		t.Errorf("address = %+v, want the Address from schemas/common.yml", user.Properties["address"])
+	}
+}
+
+func TestLoadCutsCycles(t *testing.T) {
+	doc, err := Load("testdata/refs/api.yml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	node := responseSchema(t, doc, "get", "/nodes", "200")
+	if node.Properties["name"] == nil {
+		t.Fatalf("node schema = %+v, want the resolved Node", node)
+	}
+	child := node.Properties["children"].Items
+	if child == nil || child.Ref != "#/Node" {
+		t.Errorf("children items = %+v, want the cut $ref #/Node", child)
+	}
+}
+
+// countingTransport serves testdata like a web server and counts the
+// requests it answers.
+type countingTransport struct {
+	requests atomic.Int32
+	offline  bool
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.offline {
+		return nil, errors.New("offline")
+	}
+	c.requests.Add(1)
+	return http.NewFileTransport(http.Dir("testdata")).RoundTrip(req)
+}
+
+func TestLoadRemoteRefs(t *testing.T) {
+	transport := &countingTransport{}
+	opts := LoadOptions{AllowRemoteRefs: true, HTTPClient: &http.Client{Transport: transport}}
+
+	doc, err := LoadWithOptions("testdata/refs/remote.yml", opts)
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+	if pet := responseSchema(t, doc, "get", "/pets", "200"); pet.Properties["name"] == nil {
+		t.Errorf("pet schema = %+v, want the Pet served from testdata/remote", pet)
+	}
+	// Both operations refer to pet.yml; the document is fetched once.
+	if n := transport.requests.Load(); n != 1 {
+		t.Errorf("fetched pet.yml %d times, want 1", n)
+	}
+}
+
+func TestLoadRemoteRefsDisabled(t *testing.T) {
+	_, err := LoadWithOptions("testdata/refs/remote.yml", LoadOptions{HTTPClient: &http.Client{Transport: &countingTransport{}}})
+	if err == nil || !strings.Contains(err.Error(), "remote $ref is disabled") {
+		t.Errorf("LoadWithOptions() error = %v, want remote refs disabled", err)
+	}
+}
+
+func TestLoadRemoteRefsFromCache(t *testing.T) {
+	transport := &countingTransport{}
+	opts := LoadOptions{AllowRemoteRefs: true, HTTPClient: &http.Client{Transport: transport}, CacheDir: t.TempDir()}
+	if _, err := LoadWithOptions("testdata/refs/remote.yml", opts); err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+
+	transport.offline = true
+	doc, err := LoadWithOptions("testdata/refs/remote.yml", opts)
+	if err != nil {
+		t.Fatalf("LoadWithOptions() offline error = %v, want the cached copy", err)
+	}
+	if pet := responseSchema(t, doc, "get", "/pets", "200"); pet.Properties["name"] == nil {
+		t.Errorf("pet schema = %+v, want the cached Pet", pet)
+	}
+}
+
+func TestLoadRemoteDefaultsServerToOrigin(t *testing.T) {
+	opts := LoadOptions{HTTPClient: &http.Client{Transport: &countingTransport{}}}
+	doc, err := LoadSource("http://fixtures.test/refs/remote.yml", opts)
+	if err != nil {
+		t.Fatalf("LoadSource() error = %v", err)
+	}
+	// remote.yml declares no servers, so requests go to where it was fetched.
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "http://fixtures.test/" {
+		t.Errorf("Servers = %+v, want http://fixtures.test/", doc.Servers)
+	}
+	if op := doc.Paths["/pets"]["get"]; len(op.Servers) != 1 || op.Servers[0].URL != "http://fixtures.test/" {
+		t.Errorf("GET /pets servers = %+v, want http://fixtures.test/", op.Servers)
+	}
+}
@@ -0,0 +1,371 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.0 and 3.1
+// (JSON Schema 2020-12). Both dialects decode into the same shape:
+// `nullable: true` and `type: [T, "null"]` both report IsNullable, and the
+// 3.0 boolean exclusiveMinimum/exclusiveMaximum become numeric bounds.
+type Schema struct {
+	// Ref is only set where a recursive reference was cut by the loader.
+	Ref         string
+	Types       []string
+	Nullable    bool
+	Format      string
+	Title       string
+	Description string
+	Pattern     string
+
+	Enum     []any
+	Const    any
+	HasConst bool
+	Default  any
+	Example  any
+	Examples []any
+
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum *float64
+	ExclusiveMaximum *float64
+	MultipleOf       *float64
+	MinLength        *int
+	MaxLength        *int
+	MinItems         *int
+	MaxItems         *int
+	UniqueItems      bool
+	MinProperties    *int
+	MaxProperties    *int
+
+	Required             []string
+	Properties           map[string]*Schema
+	AdditionalProperties *Schema
+	// NoAdditionalProperties is set by `additionalProperties: false`.
+	NoAdditionalProperties bool
+	Items                  *Schema
+	PrefixItems            []*Schema
+	AllOf                  []*Schema
+	OneOf                  []*Schema
+	AnyOf                  []*Schema
+	Not                    *Schema
+	Defs                   map[string]*Schema
+
+	ReadOnly   bool
+	WriteOnly  bool
+	Deprecated bool
+}
+
+// schemaRaw mirrors the YAML layout; fields whose shape differs between
+// OpenAPI 3.0 and 3.1 are kept as nodes and interpreted in UnmarshalYAML.
+type schemaRaw struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 yaml.Node          `yaml:"type"`
+	Nullable             bool               `yaml:"nullable"`
+	Format               string             `yaml:"format"`
+	Title                string             `yaml:"title"`
+	Description          string             `yaml:"description"`
+	Pattern              string             `yaml:"pattern"`
+	Enum                 []any              `yaml:"enum"`
+	Const                yaml.Node          `yaml:"const"`
+	Default              any                `yaml:"default"`
+	Example              any                `yaml:"example"`
+	Examples             []any              `yaml:"examples"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	ExclusiveMinimum     yaml.Node          `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum     yaml.Node          `yaml:"exclusiveMaximum"`
+	MultipleOf           *float64           `yaml:"multipleOf"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	UniqueItems          bool               `yaml:"uniqueItems"`
+	MinProperties        *int               `yaml:"minProperties"`
+	MaxProperties        *int               `yaml:"maxProperties"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties yaml.Node          `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	PrefixItems          []*Schema          `yaml:"prefixItems"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Not                  *Schema            `yaml:"not"`
+	Defs                 map[string]*Schema `yaml:"$defs"`
+	ReadOnly             bool               `yaml:"readOnly"`
+	WriteOnly            bool               `yaml:"writeOnly"`
+	Deprecated           bool               `yaml:"deprecated"`
+}
+
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	var raw schemaRaw
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	*s = Schema{
+		Ref:           raw.Ref,
+		Nullable:      raw.Nullable,
+		Format:        raw.Format,
+		Title:         raw.Title,
+		Description:   raw.Description,
+		Pattern:       raw.Pattern,
+		Enum:          raw.Enum,
+		Default:       raw.Default,
+		Example:       raw.Example,
+		Examples:      raw.Examples,
+		Minimum:       raw.Minimum,
+		Maximum:       raw.Maximum,
+		MultipleOf:    raw.MultipleOf,
+		MinLength:     raw.MinLength,
+		MaxLength:     raw.MaxLength,
+		MinItems:      raw.MinItems,
+		MaxItems:      raw.MaxItems,
+		UniqueItems:   raw.UniqueItems,
+		MinProperties: raw.MinProperties,
+		MaxProperties: raw.MaxProperties,
+		Required:      raw.Required,
+		Properties:    raw.Properties,
+		Items:         raw.Items,
+		PrefixItems:   raw.PrefixItems,
+		AllOf:         raw.AllOf,
+		OneOf:         raw.OneOf,
+		AnyOf:         raw.AnyOf,
+		Not:           raw.Not,
+		Defs:          raw.Defs,
+		ReadOnly:      raw.ReadOnly,
+		WriteOnly:     raw.WriteOnly,
+		Deprecated:    raw.Deprecated,
+	}
+
+	switch raw.Type.Kind {
+	case yaml.ScalarNode:
+		s.Types = []string{raw.Type.Value}
+	case yaml.SequenceNode:
+		if err := raw.Type.Decode(&s.Types); err != nil {
+			return err
+		}
+	}
+
+	if raw.Const.Kind != 0 {
+		s.HasConst = true
+		if err := raw.Const.Decode(&s.Const); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if s.ExclusiveMinimum, err = exclusiveBound(raw.ExclusiveMinimum, s.Minimum); err != nil {
+		return err
+	}
+	if s.ExclusiveMinimum != nil && raw.ExclusiveMinimum.Tag == "!!bool" {
+		s.Minimum = nil
+	}
+	if s.ExclusiveMaximum, err = exclusiveBound(raw.ExclusiveMaximum, s.Maximum); err != nil {
+		return err
+	}
+	if s.ExclusiveMaximum != nil && raw.ExclusiveMaximum.Tag == "!!bool" {
+		s.Maximum = nil
+	}
+
+	switch {
+	case raw.AdditionalProperties.Kind == 0:
+	case raw.AdditionalProperties.Tag == "!!bool":
+		var allowed bool
+		if err := raw.AdditionalProperties.Decode(&allowed); err != nil {
+			return err
+		}
+		s.NoAdditionalProperties = !allowed
+	default:
+		s.AdditionalProperties = &Schema{}
+		if err := raw.AdditionalProperties.Decode(s.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exclusiveBound reads exclusiveMinimum/exclusiveMaximum in either form:
+// a number (3.1) or a boolean modifying minimum/maximum (3.0).
+func exclusiveBound(node yaml.Node, inclusive *float64) (*float64, error) {
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	if node.Tag == "!!bool" {
+		var on bool
+		if err := node.Decode(&on); err != nil {
+			return nil, err
+		}
+		if !on || inclusive == nil {
+			return nil, nil
+		}
+		v := *inclusive
+		return &v, nil
+	}
+	var v float64
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// MarshalJSON renders the schema back in JSON Schema form so the endpoint
+// detail pane shows the familiar keywords.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	out := map[string]any{}
+	set := func(key string, v any, present bool) {
+		if present {
+			out[key] = v
+		}
+	}
+
+	set("$ref", s.Ref, s.Ref != "")
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		out["type"] = s.Types[0]
+	default:
+		out["type"] = s.Types
+	}
+	set("nullable", true, s.Nullable)
+	set("format", s.Format, s.Format != "")
+	set("title", s.Title, s.Title != "")
+	set("description", s.Description, s.Description != "")
+	set("pattern", s.Pattern, s.Pattern != "")
+	set("enum", s.Enum, len(s.Enum) > 0)
+	set("const", s.Const, s.HasConst)
+	set("default", s.Default, s.Default != nil)
+	set("example", s.Example, s.Example != nil)
+	set("examples", s.Examples, len(s.Examples) > 0)
+	set("minimum", s.Minimum, s.Minimum != nil)
+	set("maximum", s.Maximum, s.Maximum != nil)
+	set("exclusiveMinimum", s.ExclusiveMinimum, s.ExclusiveMinimum != nil)
+	set("exclusiveMaximum", s.ExclusiveMaximum, s.ExclusiveMaximum != nil)
+	set("multipleOf", s.MultipleOf, s.MultipleOf != nil)
+	set("minLength", s.MinLength, s.MinLength != nil)
+	set("maxLength", s.MaxLength, s.MaxLength != nil)
+	set("minItems", s.MinItems, s.MinItems != nil)
+	set("maxItems", s.MaxItems, s.MaxItems != nil)
+	set("uniqueItems", true, s.UniqueItems)
+	set("minProperties", s.MinProperties, s.MinProperties != nil)
+	set("maxProperties", s.MaxProperties, s.MaxProperties != nil)
+	set("required", s.Required, len(s.Required) > 0)
+	set("properties", s.Properties, len(s.Properties) > 0)
+	set("additionalProperties", s.AdditionalProperties, s.AdditionalProperties != nil)
+	set("additionalProperties", false, s.NoAdditionalProperties)
+	set("items", s.Items, s.Items != nil)
+	set("prefixItems", s.PrefixItems, len(s.PrefixItems) > 0)
+	set("allOf", s.AllOf, len(s.AllOf) > 0)
+	set("oneOf", s.OneOf, len(s.OneOf) > 0)
+	set("anyOf", s.AnyOf, len(s.AnyOf) > 0)
+	set("not", s.Not, s.Not != nil)
+	set("$defs", s.Defs, len(s.Defs) > 0)
+	set("readOnly", true, s.ReadOnly)
+	set("writeOnly", true, s.WriteOnly)
+	set("deprecated", true, s.Deprecated)
+
+	return json.Marshal(out)
+}
+
+// IsNullable reports whether null is an accepted value.
+func (s *Schema) IsNullable() bool {
+	if s == nil {
+		return false
+	}
+	return s.Nullable || slices.Contains(s.Types, "null")
+}
+
+// HasType reports whether t is one of the declared types.
+func (s *Schema) HasType(t string) bool {
+	return s != nil && slices.Contains(s.Types, t)
+}
+
+// PrimaryType returns the first declared non-null type, inferring "object"
+// or "array" from the keywords when `type` is omitted.
+func (s *Schema) PrimaryType() string {
+	if s == nil {
+		return ""
+	}
+	for _, t := range s.Types {
+		if t != "null" {
+			return t
+		}
+	}
+	switch {
+	case len(s.Properties) > 0 || s.AdditionalProperties != nil:
+		return "object"
+	case s.Items != nil || len(s.PrefixItems) > 0:
+		return "array"
+	case len(s.Types) == 1:
+		return "null"
+	}
+	return ""
+}
+
+// TypeString is a compact label such as "integer", "string|null" or
+// "string(uuid)" used by the parameter form.
+func (s *Schema) TypeString() string {
+	if s == nil {
+		return ""
+	}
+	types := slices.Clone(s.Types)
+	if len(types) == 0 {
+		if t := s.PrimaryType(); t != "" {
+			types = []string{t}
+		}
+	}
+	if s.Nullable && !slices.Contains(types, "null") {
+		types = append(types, "null")
+	}
+	label := strings.Join(types, "|")
+	if s.Format != "" {
+		label += "(" + s.Format + ")"
+	}
+	if label == "" && s.HasConst {
+		label = "const"
+	}
+	return label
+}
+
+// SampleValue returns the most specific example value the schema declares:
+// const, then example, then the first of examples, default and enum.
+func (s *Schema) SampleValue() (any, bool) {
+	switch {
+	case s == nil:
+		return nil, false
+	case s.HasConst:
+		return s.Const, true
+	case s.Example != nil:
+		return s.Example, true
+	case len(s.Examples) > 0:
+		return s.Examples[0], true
+	case s.Default != nil:
+		return s.Default, true
+	case len(s.Enum) > 0:
+		return s.Enum[0], true
+	}
+	return nil, false
+}
+
+// FormatSample renders a sample value for single-line display.
+func FormatSample(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return "null"
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(b)
+	}
+}
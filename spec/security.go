@@ -0,0 +1,72 @@
+package spec
+
+import "strings"
+
+// SecurityScheme is an entry of `components.securitySchemes`.
+type SecurityScheme struct {
+	Type         string     `yaml:"type"`
+	Description  string     `yaml:"description"`
+	Name         string     `yaml:"name"`
+	In           string     `yaml:"in"`
+	Scheme       string     `yaml:"scheme"`
+	BearerFormat string     `yaml:"bearerFormat"`
+	Flows        OAuthFlows `yaml:"flows"`
+}
+
+type OAuthFlows struct {
+	ClientCredentials *OAuthFlow `yaml:"clientCredentials"`
+	AuthorizationCode *OAuthFlow `yaml:"authorizationCode"`
+	Implicit          *OAuthFlow `yaml:"implicit"`
+	Password          *OAuthFlow `yaml:"password"`
+}
+
+type OAuthFlow struct {
+	AuthorizationURL string            `yaml:"authorizationUrl"`
+	TokenURL         string            `yaml:"tokenUrl"`
+	RefreshURL       string            `yaml:"refreshUrl"`
+	Scopes           map[string]string `yaml:"scopes"`
+}
+
+// SecurityRequirement maps scheme names to the scopes they need. All
+// schemes in one requirement apply together; an empty requirement means
+// the operation can also be called anonymously.
+type SecurityRequirement map[string][]string
+
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// Label is a short human readable description such as "HTTP bearer" or
+// "API key (header X-Api-Key)".
+func (s SecurityScheme) Label() string {
+	switch strings.ToLower(s.Type) {
+	case "apikey":
+		return "API key (" + s.In + " " + s.Name + ")"
+	case "http":
+		return "HTTP " + strings.ToLower(s.Scheme)
+	case "oauth2":
+		if s.Flows.ClientCredentials != nil {
+			return "OAuth2 client credentials"
+		}
+		return "OAuth2"
+	default:
+		return s.Type
+	}
+}
+
+// ID identifies the spec for per-spec storage such as credentials and
+// presets: "title@version" when the document declares them, otherwise
+// the source it was loaded from.
+func (s *OpenApiSpec) ID() string {
+	if s == nil {
+		return ""
+	}
+	if t := strings.TrimSpace(s.Info.Title); t != "" {
+		if v := strings.TrimSpace(s.Info.Version); v != "" {
+			return t + "@" + v
+		}
+		return t
+	}
+	return s.Source
+}
@@ -0,0 +1,30 @@
+package spec
+
+import (
+	"sort"
+	"strings"
+)
+
+// VariableNames returns the server variable names in a stable order.
+func (s Server) VariableNames() []string {
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand substitutes `{name}` placeholders in the server URL. Missing or
+// empty values fall back to the variable's default.
+func (s Server) Expand(values map[string]string) string {
+	out := s.URL
+	for name, v := range s.Variables {
+		val := strings.TrimSpace(values[name])
+		if val == "" {
+			val = v.Default
+		}
+		out = strings.ReplaceAll(out, "{"+name+"}", val)
+	}
+	return out
+}
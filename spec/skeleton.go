@@ -0,0 +1,407 @@
+package spec
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// maxSkeletonDepth stops the skeleton of deeply nested or self-referencing
+// schemas.
+const maxSkeletonDepth = 8
+
+// SkeletonOptions controls Skeleton.
+type SkeletonOptions struct {
+	// Full includes every property; otherwise only required ones.
+	Full bool
+	// Variant picks the alternative of each oneOf/anyOf, counted modulo the
+	// number of alternatives, so increasing it cycles through them.
+	Variant int
+}
+
+// Skeleton builds a value shaped like the schema for the user to fill in.
+// Values the schema declares (const, example, examples, default, enum)
+// are used as they are; otherwise objects get their properties, arrays
+// one item, and scalars a placeholder of their type and format. allOf
+// parts are merged, and one alternative of oneOf/anyOf is picked.
+// Read-only properties are left out, as they are not sent.
+func (s *Schema) Skeleton(opts SkeletonOptions) any {
+	return s.skeleton(opts, 0)
+}
+
+func (s *Schema) skeleton(opts SkeletonOptions, depth int) any {
+	if s == nil || s.Ref != "" || depth > maxSkeletonDepth {
+		return nil
+	}
+	if v, ok := s.SampleValue(); ok {
+		return v
+	}
+
+	if len(s.AllOf) > 0 {
+		return s.mergedAllOf().skeleton(opts, depth+1)
+	}
+	if alts := alternatives(s); len(alts) > 0 {
+		alt := alts[opts.Variant%len(alts)]
+		// Keep the keywords next to oneOf, such as shared properties.
+		merged := &Schema{AllOf: []*Schema{s.withoutAlternatives(), alt}}
+		return merged.mergedAllOf().skeleton(opts, depth+1)
+	}
+
+	switch s.PrimaryType() {
+	case "object":
+		obj := map[string]any{}
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			prop := s.Properties[name]
+			if prop == nil || prop.ReadOnly || (!opts.Full && !slices.Contains(s.Required, name)) {
+				continue
+			}
+			obj[name] = prop.skeleton(opts, depth+1)
+		}
+		return obj
+	case "array":
+		if len(s.PrefixItems) > 0 {
+			items := make([]any, 0, len(s.PrefixItems))
+			for _, item := range s.PrefixItems {
+				items = append(items, item.skeleton(opts, depth+1))
+			}
+			return items
+		}
+		if s.Items == nil {
+			return []any{}
+		}
+		n := 1
+		if s.MinItems != nil && *s.MinItems > n {
+			n = *s.MinItems
+		}
+		items := make([]any, 0, n)
+		for range n {
+			items = append(items, s.Items.skeleton(opts, depth+1))
+		}
+		return items
+	case "string":
+		return stringSkeleton(s.Format)
+	case "integer":
+		return int64(numberSkeleton(s))
+	case "number":
+		return numberSkeleton(s)
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// alternatives returns the oneOf or anyOf schemas, without a plain
+// `type: null` one, which makes a poor starting point.
+func alternatives(s *Schema) []*Schema {
+	alts := s.OneOf
+	if len(alts) == 0 {
+		alts = s.AnyOf
+	}
+	var out []*Schema
+	for _, alt := range alts {
+		if alt != nil && !(len(alt.Types) == 1 && alt.Types[0] == "null") {
+			out = append(out, alt)
+		}
+	}
+	if len(out) == 0 {
+		return alts
+	}
+	return out
+}
+
+func (s *Schema) withoutAlternatives() *Schema {
+	c := *s
+	c.OneOf, c.AnyOf = nil, nil
+	return &c
+}
+
+// mergedAllOf combines s and its allOf parts into one schema: properties
+// and required names are joined, and other keywords are taken from the
+// first part that sets them.
+func (s *Schema) mergedAllOf() *Schema {
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = maps.Clone(s.Properties)
+	merged.Required = slices.Clone(s.Required)
+	for _, part := range s.AllOf {
+		if part == nil {
+			continue
+		}
+		if len(part.AllOf) > 0 {
+			part = part.mergedAllOf()
+		}
+		if len(part.Properties) > 0 && merged.Properties == nil {
+			merged.Properties = map[string]*Schema{}
+		}
+		for name, prop := range part.Properties {
+			if _, ok := merged.Properties[name]; !ok {
+				merged.Properties[name] = prop
+			}
+		}
+		for _, name := range part.Required {
+			if !slices.Contains(merged.Required, name) {
+				merged.Required = append(merged.Required, name)
+			}
+		}
+		if len(merged.Types) == 0 {
+			merged.Types = part.Types
+		}
+		if merged.Format == "" {
+			merged.Format = part.Format
+		}
+		if merged.Items == nil {
+			merged.Items = part.Items
+		}
+		if len(merged.OneOf) == 0 && len(merged.AnyOf) == 0 {
+			merged.OneOf, merged.AnyOf = part.OneOf, part.AnyOf
+		}
+		if _, ok := merged.SampleValue(); !ok {
+			merged.HasConst, merged.Const = part.HasConst, part.Const
+			merged.Example, merged.Examples = part.Example, part.Examples
+			merged.Default, merged.Enum = part.Default, part.Enum
+		}
+	}
+	return &merged
+}
+
+func stringSkeleton(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	}
+	return "string"
+}
+
+// numberSkeleton is 0 moved into the bounds.
+func numberSkeleton(s *Schema) float64 {
+	v := 0.0
+	switch {
+	case s.Minimum != nil && v < *s.Minimum:
+		v = *s.Minimum
+	case s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum:
+		v = *s.ExclusiveMinimum + 1
+	}
+	switch {
+	case s.Maximum != nil && v > *s.Maximum:
+		v = *s.Maximum
+	case s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum:
+		v = *s.ExclusiveMaximum - 1
+	}
+	return v
+}
+
+// Flatten merges the allOf parts of s into one schema and picks the
+// oneOf/anyOf alternative that fits v best, so the result can be walked by
+// its properties and items. With no value the first alternative is used,
+// as in Skeleton.
+func (s *Schema) Flatten(v any) *Schema {
+	flat := s
+	for range maxSkeletonDepth {
+		if flat == nil {
+			return nil
+		}
+		if len(flat.AllOf) > 0 {
+			flat = flat.mergedAllOf()
+			continue
+		}
+		alts := alternatives(flat)
+		if len(alts) == 0 {
+			return flat
+		}
+		best, bestScore := alts[0], fitScore(alts[0], v)
+		for _, alt := range alts[1:] {
+			if score := fitScore(alt, v); score > bestScore {
+				best, bestScore = alt, score
+			}
+		}
+		flat = (&Schema{AllOf: []*Schema{flat.withoutAlternatives(), best}}).mergedAllOf()
+	}
+	return flat
+}
+
+// fitScore rates how well v matches the type, constants and properties of
+// s; higher is better.
+func fitScore(s *Schema, v any) int {
+	if v == nil {
+		return 0
+	}
+	if len(s.AllOf) > 0 {
+		s = s.mergedAllOf()
+	}
+	score := 0
+	if t := s.PrimaryType(); t != "" {
+		kind := JSONKind(v)
+		if t == kind || (t == "number" && kind == "integer") {
+			score += 2
+		} else {
+			score -= 10
+		}
+	}
+	obj, isObject := v.(map[string]any)
+	if _, isArray := v.([]any); !isObject && !isArray {
+		// Only scalars compare with ==.
+		if (s.HasConst && s.Const == v) || slices.Contains(s.Enum, v) {
+			score += 3
+		}
+	}
+	if isObject {
+		for _, name := range s.Required {
+			if _, ok := obj[name]; ok {
+				score++
+			} else {
+				score -= 2
+			}
+		}
+		for name := range obj {
+			if _, ok := s.Properties[name]; ok {
+				score++
+			}
+		}
+	}
+	return score
+}
+
+// JSONKind names the JSON Schema type of a decoded JSON value: "integer"
+// for whole numbers, "number" for the others.
+func JSONKind(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case int, int64, uint64:
+		return "integer"
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if t == float64(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return ""
+}
+
+// HasAlternatives reports whether a Skeleton of the schema depends on
+// SkeletonOptions.Variant.
+func (s *Schema) HasAlternatives() bool {
+	return s.hasAlternatives(0)
+}
+
+func (s *Schema) hasAlternatives(depth int) bool {
+	if s == nil || depth > maxSkeletonDepth {
+		return false
+	}
+	if len(s.OneOf) > 1 || len(s.AnyOf) > 1 {
+		return true
+	}
+	children := slices.Concat(s.AllOf, s.PrefixItems, []*Schema{s.Items})
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		children = append(children, s.Properties[name])
+	}
+	for _, c := range children {
+		if c.hasAlternatives(depth + 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONMediaType returns the JSON media type of the body: application/json,
+// then any +json type, falling back to the first one by name.
+func (b *RequestBody) JSONMediaType() (MediaType, bool) {
+	name := b.JSONMediaTypeName()
+	if name == "" {
+		return MediaType{}, false
+	}
+	return b.Content[name], true
+}
+
+// JSONMediaTypeName is the name of the media type JSONMediaType picks, the
+// Content-Type the body is sent with; "" when the body has no content.
+func (b *RequestBody) JSONMediaTypeName() string {
+	if b == nil || len(b.Content) == 0 {
+		return ""
+	}
+	if _, ok := b.Content["application/json"]; ok {
+		return "application/json"
+	}
+	names := slices.Sorted(maps.Keys(b.Content))
+	for _, name := range names {
+		if strings.HasSuffix(strings.SplitN(name, ";", 2)[0], "+json") {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// ExampleBody renders the example the media type declares as a JSON
+// body, or "" when it has none.
+func (b *RequestBody) ExampleBody() string {
+	mt, ok := b.JSONMediaType()
+	if !ok {
+		return ""
+	}
+	v, ok := mt.sample()
+	if !ok {
+		return ""
+	}
+	return indentJSON(v)
+}
+
+// SkeletonBody renders a skeleton of the body's schema as JSON, or "" when
+// the body has no schema.
+func (b *RequestBody) SkeletonBody(opts SkeletonOptions) string {
+	mt, ok := b.JSONMediaType()
+	if !ok || mt.Schema == nil {
+		return ""
+	}
+	return indentJSON(mt.Schema.Skeleton(opts))
+}
+
+func indentJSON(v any) string {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// sample is the media type's example, or its first named example.
+func (mt MediaType) sample() (any, bool) {
+	if mt.Example != nil {
+		return mt.Example, true
+	}
+	for _, name := range slices.Sorted(maps.Keys(mt.Examples)) {
+		if v := mt.Examples[name].Value; v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,222 @@
+package spec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StdinSource is the `--spec` value that reads the document from standard input.
+const StdinSource = "-"
+
+// LoadSource loads a spec from a file path, an http(s) URL or StdinSource.
+// Remote documents are cached on disk and revalidated with ETag /
+// Last-Modified; when the network is unavailable the cached copy is used
+// and the returned spec is marked Stale.
+func LoadSource(src string, opts LoadOptions) (*OpenApiSpec, error) {
+	switch {
+	case src == StdinSource:
+		return loadStdin(opts)
+	case isRemote(src):
+		return loadRemote(src, opts)
+	default:
+		return LoadWithOptions(src, opts)
+	}
+}
+
+func loadStdin(opts LoadOptions) (*OpenApiSpec, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := newRefResolver(opts)
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse stdin: %w", err)
+	}
+	// Relative references in a piped document resolve from the working directory.
+	doc := resolver.addDocument(filepath.Join(cwd, "stdin"), root, false)
+	out, err := buildSpec(resolver, doc)
+	if err != nil {
+		return nil, err
+	}
+	out.Source = StdinSource
+	return out, nil
+}
+
+func loadRemote(rawURL string, opts LoadOptions) (*OpenApiSpec, error) {
+	// A remote root only makes sense with its sibling documents reachable.
+	opts.AllowRemoteRefs = true
+	resolver := newRefResolver(opts)
+
+	data, stale, err := fetchCached(opts.httpClient(), rawURL, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", rawURL, err)
+	}
+
+	doc := resolver.addDocument(rawURL, root, false)
+	out, err := buildSpec(resolver, doc)
+	if err != nil {
+		return nil, err
+	}
+	out.Source = rawURL
+	out.Stale = stale
+	if err := resolveRelativeServers(out, rawURL); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// resolveRelativeServers turns server URLs such as `/v1` into absolute URLs
+// against the location the spec was fetched from. A spec without servers
+// gets the default `/` that OpenAPI implies, so it is served from there.
+func resolveRelativeServers(doc *OpenApiSpec, base string) error {
+	bu, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	// Plain concatenation keeps `{variable}` placeholders unescaped.
+	origin := bu.Scheme + "://" + bu.Host
+	fix := func(servers []Server) {
+		for i, s := range servers {
+			switch {
+			case isRemote(s.URL) || strings.Contains(s.URL, "://"):
+			case strings.HasPrefix(s.URL, "/"):
+				servers[i].URL = origin + s.URL
+			default:
+				servers[i].URL = origin + path.Join(path.Dir(bu.Path), s.URL)
+			}
+		}
+	}
+
+	if len(doc.Servers) == 0 {
+		doc.Servers = []Server{{URL: "/"}}
+	}
+	fix(doc.Servers)
+	for _, methods := range doc.Paths {
+		for method, op := range methods {
+			if len(op.Servers) == 0 {
+				op.Servers = doc.Servers
+				methods[method] = op
+			}
+			fix(op.Servers)
+		}
+	}
+	return nil
+}
+
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// fetchCached GETs rawURL, revalidating against the on-disk copy in dir.
+// It reports stale=true when the cached copy was used because the request failed.
+func fetchCached(client *http.Client, rawURL, dir string) ([]byte, bool, error) {
+	if dir == "" {
+		data, err := fetchRemote(client, rawURL)
+		return data, false, err
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	base := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	bodyPath, metaPath := base+".body", base+".json"
+
+	var meta cacheMeta
+	cached, cacheErr := os.ReadFile(bodyPath)
+	if cacheErr == nil {
+		if b, err := os.ReadFile(metaPath); err == nil {
+			_ = json.Unmarshal(b, &meta)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if cacheErr == nil {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			return cached, true, nil
+		}
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cacheErr == nil:
+		return cached, false, nil
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		if cacheErr == nil && res.StatusCode >= 500 {
+			return cached, true, nil
+		}
+		return nil, false, fmt.Errorf("GET %s: %s", rawURL, res.Status)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	meta = cacheMeta{
+		URL:          rawURL,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	// A failed cache write only costs offline support for the next run.
+	_ = writeCache(dir, bodyPath, metaPath, data, meta)
+	return data, false, nil
+}
+
+func writeCache(dir, bodyPath, metaPath string, data []byte, meta cacheMeta) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(bodyPath, data, 0o644); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, b, 0o644)
+}
+
+// DefaultCacheDir is where remote specs are cached between runs.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "clyst", "specs")
+}
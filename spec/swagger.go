@@ -0,0 +1,524 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// isSwagger2 reports whether a parsed document root declares `swagger: "2.0"`.
+func isSwagger2(root any) bool {
+	m, ok := root.(map[string]any)
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(fmt.Sprint(m["swagger"]), "2")
+}
+
+// schemaKeys are the Swagger 2.0 parameter/header fields that move into
+// `schema` in OpenAPI 3.
+var schemaKeys = []string{
+	"type", "format", "items", "collectionFormat", "default", "enum",
+	"maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+	"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "multipleOf",
+}
+
+// convertSwagger2 rewrites a Swagger 2.0 document tree into the equivalent
+// OpenAPI 3 shape so the regular loader can decode it. external returns
+// the parameters of another file the document refers to.
+func convertSwagger2(root map[string]any, external func(location string) map[string]any) map[string]any {
+	c := swaggerConverter{
+		root:     root,
+		external: external,
+		consumes: stringList(root["consumes"]),
+		produces: stringList(root["produces"]),
+	}
+	if len(c.consumes) == 0 {
+		c.consumes = []string{"application/json"}
+	}
+	if len(c.produces) == 0 {
+		c.produces = []string{"application/json"}
+	}
+	return c.convert()
+}
+
+type swaggerConverter struct {
+	root     map[string]any
+	external func(location string) map[string]any
+	consumes []string
+	produces []string
+}
+
+func (c swaggerConverter) convert() map[string]any {
+	out := map[string]any{"openapi": "3.0.3"}
+	for _, k := range []string{"info", "tags", "security", "externalDocs", "base_url", "baseUrl"} {
+		if v, ok := c.root[k]; ok {
+			out[k] = v
+		}
+	}
+	if servers := c.servers(); len(servers) > 0 {
+		out["servers"] = servers
+	}
+
+	if components := c.components(); len(components) > 0 {
+		out["components"] = components
+	}
+
+	if paths, ok := c.root["paths"].(map[string]any); ok {
+		conv := make(map[string]any, len(paths))
+		for p, item := range paths {
+			if im, ok := item.(map[string]any); ok {
+				conv[p] = c.pathItem(im)
+			} else {
+				conv[p] = item
+			}
+		}
+		out["paths"] = conv
+	}
+
+	rewriteSwaggerRefs(out, c.bodyParamNames())
+	return out
+}
+
+// convertSwaggerFragment prepares a file a Swagger 2.0 document refers to
+// that is not a document of its own, such as a file of shared
+// definitions. Its refs are rewritten as the document's are, and its
+// definitions, parameters and responses are also placed under
+// `components`, where the rewritten refs into the file point.
+func convertSwaggerFragment(root map[string]any) map[string]any {
+	c := swaggerConverter{root: root, consumes: []string{"application/json"}, produces: []string{"application/json"}}
+	out := make(map[string]any, len(root)+1)
+	for k, v := range root {
+		out[k] = v
+	}
+	if _, ok := out["components"]; !ok {
+		if components := c.components(); len(components) > 0 {
+			out["components"] = components
+		}
+	}
+	rewriteSwaggerRefs(out, c.bodyParamNames())
+	return out
+}
+
+// components collects the reusable definitions under their OpenAPI 3
+// names.
+func (c swaggerConverter) components() map[string]any {
+	components := map[string]any{}
+	if defs, ok := c.root["definitions"].(map[string]any); ok {
+		components["schemas"] = defs
+	}
+	if params, ok := c.root["parameters"].(map[string]any); ok {
+		plain := map[string]any{}
+		bodies := map[string]any{}
+		for name, raw := range params {
+			p, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch p["in"] {
+			case "body":
+				bodies[name] = c.requestBody(p, c.consumes)
+			case "formData":
+				// Form fields are inlined where referenced; see operation().
+			default:
+				plain[name] = convertParameter(p)
+			}
+		}
+		if len(plain) > 0 {
+			components["parameters"] = plain
+		}
+		if len(bodies) > 0 {
+			components["requestBodies"] = bodies
+		}
+	}
+	if responses, ok := c.root["responses"].(map[string]any); ok {
+		conv := make(map[string]any, len(responses))
+		for name, r := range responses {
+			conv[name] = c.response(r, c.produces)
+		}
+		components["responses"] = conv
+	}
+	if defs, ok := c.root["securityDefinitions"].(map[string]any); ok {
+		schemes := make(map[string]any, len(defs))
+		for name, d := range defs {
+			if dm, ok := d.(map[string]any); ok {
+				schemes[name] = convertSecurityScheme(dm)
+			}
+		}
+		components["securitySchemes"] = schemes
+	}
+	return components
+}
+
+func (c swaggerConverter) servers() []any {
+	host, _ := c.root["host"].(string)
+	basePath, _ := c.root["basePath"].(string)
+	if host == "" && basePath == "" {
+		return nil
+	}
+	if host == "" {
+		return []any{map[string]any{"url": basePath}}
+	}
+
+	schemes := stringList(c.root["schemes"])
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	out := make([]any, 0, len(schemes))
+	for _, s := range schemes {
+		out = append(out, map[string]any{"url": s + "://" + host + basePath})
+	}
+	return out
+}
+
+func (c swaggerConverter) bodyParamNames() map[string]bool {
+	names := map[string]bool{}
+	if params, ok := c.root["parameters"].(map[string]any); ok {
+		for name, raw := range params {
+			if p, ok := raw.(map[string]any); ok && p["in"] == "body" {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// lookupParameter follows a `#/parameters/<name>` reference, in the source
+// document or in another file.
+func (c swaggerConverter) lookupParameter(p map[string]any) map[string]any {
+	ref, ok := p["$ref"].(string)
+	if !ok {
+		return p
+	}
+	location, fragment, _ := strings.Cut(ref, "#")
+	name, ok := strings.CutPrefix(fragment, "/parameters/")
+	if !ok {
+		return p
+	}
+	params, _ := c.root["parameters"].(map[string]any)
+	if location != "" {
+		if c.external == nil {
+			return p
+		}
+		params = c.external(location)
+	}
+	if target, ok := params[name].(map[string]any); ok {
+		return target
+	}
+	return p
+}
+
+func (c swaggerConverter) pathItem(item map[string]any) map[string]any {
+	out := map[string]any{}
+	shared := mapList(item["parameters"])
+	for key, v := range item {
+		if key == "parameters" {
+			continue
+		}
+		op, ok := v.(map[string]any)
+		if _, isMethod := httpMethods[strings.ToLower(key)]; !isMethod || !ok {
+			out[key] = v
+			continue
+		}
+		out[key] = c.operation(op, shared)
+	}
+	return out
+}
+
+func (c swaggerConverter) operation(op map[string]any, shared []map[string]any) map[string]any {
+	out := map[string]any{}
+	for k, v := range op {
+		switch k {
+		case "parameters", "responses", "consumes", "produces", "schemes":
+		default:
+			out[k] = v
+		}
+	}
+
+	consumes := stringList(op["consumes"])
+	if len(consumes) == 0 {
+		consumes = c.consumes
+	}
+	produces := stringList(op["produces"])
+	if len(produces) == 0 {
+		produces = c.produces
+	}
+
+	var (
+		params     []any
+		bodyParam  map[string]any
+		formParams []map[string]any
+		bodyRef    string
+	)
+	for _, p := range mergeSwaggerParams(shared, mapList(op["parameters"])) {
+		resolved := c.lookupParameter(p)
+		switch resolved["in"] {
+		case "body":
+			if ref, ok := p["$ref"].(string); ok {
+				bodyRef = swaggerRefTarget(ref, map[string]bool{swaggerRefName(ref): true})
+			} else {
+				bodyParam = resolved
+			}
+		case "formData":
+			formParams = append(formParams, resolved)
+		default:
+			if _, isRef := p["$ref"]; isRef {
+				params = append(params, p)
+			} else {
+				params = append(params, convertParameter(p))
+			}
+		}
+	}
+
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	switch {
+	case bodyRef != "":
+		out["requestBody"] = map[string]any{"$ref": bodyRef}
+	case bodyParam != nil:
+		out["requestBody"] = c.requestBody(bodyParam, consumes)
+	case len(formParams) > 0:
+		out["requestBody"] = c.formBody(formParams, consumes)
+	}
+
+	if responses, ok := op["responses"].(map[string]any); ok {
+		conv := make(map[string]any, len(responses))
+		for code, r := range responses {
+			conv[code] = c.response(r, produces)
+		}
+		out["responses"] = conv
+	}
+
+	return out
+}
+
+func (c swaggerConverter) requestBody(p map[string]any, consumes []string) map[string]any {
+	content := map[string]any{}
+	for _, ct := range consumes {
+		content[ct] = map[string]any{"schema": p["schema"]}
+	}
+	out := map[string]any{"content": content}
+	if req, ok := p["required"].(bool); ok {
+		out["required"] = req
+	}
+	if desc, ok := p["description"]; ok {
+		out["description"] = desc
+	}
+	return out
+}
+
+func (c swaggerConverter) formBody(params []map[string]any, consumes []string) map[string]any {
+	props := map[string]any{}
+	var required []any
+	multipart := false
+	for _, p := range params {
+		name := fmt.Sprint(p["name"])
+		schema := convertParameter(p)["schema"]
+		props[name] = schema
+		if req, _ := p["required"].(bool); req {
+			required = append(required, name)
+		}
+		if p["type"] == "file" {
+			multipart = true
+		}
+	}
+	for _, ct := range consumes {
+		if strings.HasPrefix(ct, "multipart/") {
+			multipart = true
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	ct := "application/x-www-form-urlencoded"
+	if multipart {
+		ct = "multipart/form-data"
+	}
+	return map[string]any{"content": map[string]any{ct: map[string]any{"schema": schema}}}
+}
+
+func (c swaggerConverter) response(raw any, produces []string) any {
+	r, ok := raw.(map[string]any)
+	if !ok {
+		return raw
+	}
+	if _, isRef := r["$ref"]; isRef {
+		return r
+	}
+
+	out := map[string]any{}
+	if desc, ok := r["description"]; ok {
+		out["description"] = desc
+	}
+	if schema, ok := r["schema"]; ok {
+		examples, _ := r["examples"].(map[string]any)
+		content := map[string]any{}
+		for _, ct := range produces {
+			media := map[string]any{"schema": schema}
+			if ex, ok := examples[ct]; ok {
+				media["example"] = ex
+			}
+			content[ct] = media
+		}
+		out["content"] = content
+	}
+	if headers, ok := r["headers"].(map[string]any); ok {
+		conv := make(map[string]any, len(headers))
+		for name, h := range headers {
+			if hm, ok := h.(map[string]any); ok {
+				conv[name] = moveSchemaKeys(hm)
+			}
+		}
+		out["headers"] = conv
+	}
+	return out
+}
+
+// convertParameter moves Swagger 2.0 inline type information into `schema`.
+func convertParameter(p map[string]any) map[string]any {
+	if _, isRef := p["$ref"]; isRef {
+		return p
+	}
+	return moveSchemaKeys(p)
+}
+
+func moveSchemaKeys(src map[string]any) map[string]any {
+	out := map[string]any{}
+	schema := map[string]any{}
+	for k, v := range src {
+		if slices.Contains(schemaKeys, k) {
+			if k != "collectionFormat" {
+				schema[k] = v
+			}
+			continue
+		}
+		out[k] = v
+	}
+	if schema["type"] == "file" {
+		schema["type"] = "string"
+		schema["format"] = "binary"
+	}
+	if len(schema) > 0 {
+		out["schema"] = schema
+	}
+	return out
+}
+
+func convertSecurityScheme(d map[string]any) map[string]any {
+	switch d["type"] {
+	case "basic":
+		out := map[string]any{"type": "http", "scheme": "basic"}
+		if desc, ok := d["description"]; ok {
+			out["description"] = desc
+		}
+		return out
+	case "oauth2":
+		flow := map[string]any{"scopes": d["scopes"]}
+		if u, ok := d["authorizationUrl"]; ok {
+			flow["authorizationUrl"] = u
+		}
+		if u, ok := d["tokenUrl"]; ok {
+			flow["tokenUrl"] = u
+		}
+		name := map[string]string{
+			"implicit":    "implicit",
+			"password":    "password",
+			"application": "clientCredentials",
+			"accessCode":  "authorizationCode",
+		}[fmt.Sprint(d["flow"])]
+		return map[string]any{"type": "oauth2", "flows": map[string]any{name: flow}}
+	default:
+		return d
+	}
+}
+
+// rewriteSwaggerRefs points references at their OpenAPI 3 locations, in
+// this document and in the files it refers to.
+func rewriteSwaggerRefs(node any, bodyParams map[string]bool) {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			v["$ref"] = swaggerRefTarget(ref, bodyParams)
+		}
+		for _, child := range v {
+			rewriteSwaggerRefs(child, bodyParams)
+		}
+	case []any:
+		for _, child := range v {
+			rewriteSwaggerRefs(child, bodyParams)
+		}
+	}
+}
+
+// swaggerRefTarget rewrites the fragment of ref. bodyParams are the names
+// of the parameters that are bodies; operation() looks up the ones in
+// other files, and elsewhere those are taken to be plain parameters.
+func swaggerRefTarget(ref string, bodyParams map[string]bool) string {
+	location, fragment, ok := strings.Cut(ref, "#")
+	if !ok {
+		return ref
+	}
+	switch {
+	case strings.HasPrefix(fragment, "/definitions/"):
+		fragment = "/components/schemas/" + strings.TrimPrefix(fragment, "/definitions/")
+	case strings.HasPrefix(fragment, "/parameters/"):
+		name := strings.TrimPrefix(fragment, "/parameters/")
+		if bodyParams[name] {
+			fragment = "/components/requestBodies/" + name
+		} else {
+			fragment = "/components/parameters/" + name
+		}
+	case strings.HasPrefix(fragment, "/responses/"):
+		fragment = "/components/responses/" + strings.TrimPrefix(fragment, "/responses/")
+	}
+	return location + "#" + fragment
+}
+
+// swaggerRefName is the last segment of a ref's pointer.
+func swaggerRefName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// mergeSwaggerParams lets operation parameters override path-level ones
+// with the same name and location.
+func mergeSwaggerParams(shared, own []map[string]any) []map[string]any {
+	out := make([]map[string]any, 0, len(shared)+len(own))
+	for _, s := range shared {
+		overridden := false
+		for _, o := range own {
+			if s["name"] != nil && s["name"] == o["name"] && s["in"] == o["in"] {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			out = append(out, s)
+		}
+	}
+	return append(out, own...)
+}
+
+func mapList(v any) []map[string]any {
+	list, _ := v.([]any)
+	out := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package spec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadSwagger2(t *testing.T) {
+	doc, err := Load("testdata/swagger/api.yml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://api.example.com/v1" {
+		t.Errorf("servers = %+v, want https://api.example.com/v1", doc.Servers)
+	}
+
+	t.Run("local body ref and definitions", func(t *testing.T) {
+		body := doc.Paths["/users"]["post"].RequestBody
+		mt, ok := body.JSONMediaType()
+		if !ok || !body.Required {
+			t.Fatalf("request body = %+v, want a required JSON body", body)
+		}
+		if mt.Schema.Properties["name"] == nil || mt.Schema.Properties["profile"].Properties["bio"] == nil {
+			t.Errorf("body schema = %+v, want User with the external Profile", mt.Schema)
+		}
+	})
+
+	t.Run("external refs", func(t *testing.T) {
+		get := doc.Paths["/users/{id}"]["get"]
+		if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].Schema.PrimaryType() != "integer" {
+			t.Errorf("parameters = %+v, want the path id from common.yml", get.Parameters)
+		}
+		if s := get.Responses["200"].Content["application/json"].Schema; s == nil || s.Properties["bio"] == nil {
+			t.Errorf("response schema = %+v, want Profile from common.yml", s)
+		}
+
+		put := doc.Paths["/users/{id}"]["put"]
+		mt, ok := put.RequestBody.JSONMediaType()
+		if !ok || mt.Schema.Properties["bio"] == nil {
+			t.Errorf("request body = %+v, want the ProfileBody from common.yml", put.RequestBody)
+		}
+		if len(put.Parameters) != 1 {
+			t.Errorf("parameters = %+v, want only the path id", put.Parameters)
+		}
+	})
+
+	t.Run("formData", func(t *testing.T) {
+		login := doc.Paths["/login"]["post"]
+		mt, ok := login.RequestBody.Content["application/x-www-form-urlencoded"]
+		if !ok {
+			t.Fatalf("content = %v, want a urlencoded form", login.RequestBody.Content)
+		}
+		var names []string
+		for name := range mt.Schema.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, []string{"password", "remember", "username"}) || !slices.Equal(mt.Schema.Required, []string{"username"}) {
+			t.Errorf("form schema = %+v, want username, password and the external remember", mt.Schema)
+		}
+		if len(login.Parameters) != 0 {
+			t.Errorf("parameters = %+v, want the form fields in the body only", login.Parameters)
+		}
+
+		avatar := doc.Paths["/avatar"]["post"].RequestBody.Content["multipart/form-data"]
+		if avatar.Schema == nil || avatar.Schema.Properties["file"].Format != "binary" {
+			t.Errorf("avatar body = %+v, want a multipart form with a binary file", avatar.Schema)
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation is a value that does not satisfy its schema. Pointer is the
+// JSON pointer of the value in the validated document, "" for the document
+// itself.
+type Violation struct {
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Pointer == "" {
+		return v.Message
+	}
+	return v.Pointer + ": " + v.Message
+}
+
+// Direction is which way a validated value travels. It decides which
+// required properties may be absent: readOnly ones in requests, as the
+// server sets them, and writeOnly ones in responses.
+type Direction int
+
+const (
+	DirectionRequest Direction = iota
+	DirectionResponse
+)
+
+// Validate checks v, a decoded JSON value, against the schema. Numbers may
+// be json.Number, float64 or integers. Keywords clyst does not know, and
+// patterns Go cannot compile, are not checked.
+func (s *Schema) Validate(v any, dir Direction) []Violation {
+	var out []Violation
+	s.validate(v, "", dir, 0, &out)
+	return out
+}
+
+// ValidateText checks a parameter value as typed: it is read as the
+// schema's type first, so "7" is an integer and "true" a boolean. Arrays
+// are read as comma-separated items.
+func (s *Schema) ValidateText(text string, dir Direction) []Violation {
+	return s.Validate(s.parseText(text), dir)
+}
+
+func (s *Schema) parseText(text string) any {
+	switch s.PrimaryType() {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(text)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(text); err == nil && (text == "true" || text == "false") {
+			return b
+		}
+	case "null":
+		if text == "null" {
+			return nil
+		}
+	case "array":
+		parts := strings.Split(text, ",")
+		items := make([]any, 0, len(parts))
+		for i, p := range parts {
+			var item *Schema
+			switch {
+			case i < len(s.PrefixItems):
+				item = s.PrefixItems[i]
+			case s.Items != nil:
+				item = s.Items
+			}
+			if item == nil {
+				items = append(items, p)
+				continue
+			}
+			items = append(items, item.parseText(p))
+		}
+		return items
+	case "object":
+		var v any
+		if json.Unmarshal([]byte(text), &v) == nil {
+			return v
+		}
+	}
+	return text
+}
+
+func (s *Schema) validate(v any, ptr string, dir Direction, depth int, out *[]Violation) {
+	if s == nil || s.Ref != "" || depth > maxValidateDepth {
+		return
+	}
+	add := func(format string, args ...any) {
+		*out = append(*out, Violation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	kind := JSONKind(v)
+	if types := s.allowedTypes(); len(types) > 0 && !typeAllowed(types, kind) {
+		add("must be %s, not %s", strings.Join(types, " or "), kind)
+		return
+	}
+	if s.HasConst && !sameValue(s.Const, v) {
+		add("must be %s", FormatSample(s.Const))
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return sameValue(e, v) }) {
+		choices := make([]string, 0, len(s.Enum))
+		for _, e := range s.Enum {
+			choices = append(choices, FormatSample(e))
+		}
+		add("must be one of %s", strings.Join(choices, ", "))
+	}
+
+	switch t := v.(type) {
+	case string:
+		s.validateString(t, add)
+	case map[string]any:
+		s.validateObject(t, ptr, dir, depth, out, add)
+	case []any:
+		s.validateArray(t, ptr, dir, depth, out, add)
+	default:
+		if f, ok := number(v); ok {
+			s.validateNumber(f, add)
+		}
+	}
+
+	for _, part := range s.AllOf {
+		part.validate(v, ptr, dir, depth+1, out)
+	}
+	if len(s.OneOf) > 0 {
+		if n := matching(s.OneOf, v, dir, depth); n != 1 {
+			add("must match exactly one of the oneOf schemas, matches %d", n)
+		}
+	}
+	if len(s.AnyOf) > 0 && matching(s.AnyOf, v, dir, depth) == 0 {
+		add("must match at least one of the anyOf schemas")
+	}
+	if s.Not != nil && len(s.Not.Validate(v, dir)) == 0 {
+		add("must not match the not schema")
+	}
+}
+
+// maxValidateDepth stops validation of very deep or self-referencing
+// schemas.
+const maxValidateDepth = 64
+
+func matching(schemas []*Schema, v any, dir Direction, depth int) int {
+	n := 0
+	for _, s := range schemas {
+		var out []Violation
+		s.validate(v, "", dir, depth+1, &out)
+		if len(out) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// allowedTypes lists the declared types, with null when nullable.
+func (s *Schema) allowedTypes() []string {
+	types := s.Types
+	if len(types) > 0 && s.Nullable && !slices.Contains(types, "null") {
+		types = append(slices.Clone(types), "null")
+	}
+	return types
+}
+
+func typeAllowed(types []string, kind string) bool {
+	return slices.Contains(types, kind) || (kind == "integer" && slices.Contains(types, "number"))
+}
+
+// sameValue compares JSON values, whatever types they were decoded to.
+func sameValue(a, b any) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+func number(v any) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func (s *Schema) validateString(v string, add func(string, ...any)) {
+	n := utf8.RuneCountInString(v)
+	if s.MinLength != nil && n < *s.MinLength {
+		add("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		add("must be at most %d characters long", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(v) {
+			add("must match the pattern %s", s.Pattern)
+		}
+	}
+	if msg := checkFormat(s.Format, v); msg != "" {
+		add("%s", msg)
+	}
+}
+
+// checkFormat checks the common string formats and returns a message when
+// v does not have the format.
+func checkFormat(format, v string) string {
+	var ok bool
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, v)
+		ok = err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, v)
+		ok = err == nil
+	case "email":
+		addr, err := mail.ParseAddress(v)
+		ok = err == nil && addr.Address == v
+	case "uuid":
+		ok = uuidPattern.MatchString(v)
+	case "uri", "url":
+		u, err := url.Parse(v)
+		ok = err == nil && u.Scheme != ""
+	case "ipv4":
+		addr, err := netip.ParseAddr(v)
+		ok = err == nil && addr.Is4()
+	case "ipv6":
+		addr, err := netip.ParseAddr(v)
+		ok = err == nil && addr.Is6()
+	default:
+		return ""
+	}
+	if ok {
+		return ""
+	}
+	return "must be a valid " + format
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func (s *Schema) validateNumber(v float64, add func(string, ...any)) {
+	if s.Format == "int32" && (v < math.MinInt32 || v > math.MaxInt32) {
+		add("must fit in a 32-bit integer")
+	}
+	if s.Minimum != nil && v < *s.Minimum {
+		add("must be at least %s", formatNumber(*s.Minimum))
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		add("must be at most %s", formatNumber(*s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+		add("must be greater than %s", formatNumber(*s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+		add("must be less than %s", formatNumber(*s.ExclusiveMaximum))
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		if q := v / *s.MultipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			add("must be a multiple of %s", formatNumber(*s.MultipleOf))
+		}
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (s *Schema) validateObject(v map[string]any, ptr string, dir Direction, depth int, out *[]Violation, add func(string, ...any)) {
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok && !s.Properties[name].omitted(dir) {
+			add("missing required property %q", name)
+		}
+	}
+	if s.MinProperties != nil && len(v) < *s.MinProperties {
+		add("must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+		add("must have at most %d properties", *s.MaxProperties)
+	}
+	for _, name := range slices.Sorted(maps.Keys(v)) {
+		child := ptr + "/" + escapePointer(name)
+		switch prop, ok := s.Properties[name]; {
+		case ok:
+			prop.validate(v[name], child, dir, depth+1, out)
+		case s.AdditionalProperties != nil:
+			s.AdditionalProperties.validate(v[name], child, dir, depth+1, out)
+		case s.NoAdditionalProperties:
+			*out = append(*out, Violation{Pointer: child, Message: "is not an allowed property"})
+		}
+	}
+}
+
+func (s *Schema) validateArray(v []any, ptr string, dir Direction, depth int, out *[]Violation, add func(string, ...any)) {
+	if s.MinItems != nil && len(v) < *s.MinItems {
+		add("must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(v) > *s.MaxItems {
+		add("must have at most %d items", *s.MaxItems)
+	}
+	if s.UniqueItems {
+	Unique:
+		for i := range v {
+			for j := range i {
+				if sameValue(v[i], v[j]) {
+					add("items %d and %d are the same", j, i)
+					break Unique
+				}
+			}
+		}
+	}
+	for i, item := range v {
+		var schema *Schema
+		switch {
+		case i < len(s.PrefixItems):
+			schema = s.PrefixItems[i]
+		default:
+			schema = s.Items
+		}
+		schema.validate(item, ptr+"/"+strconv.Itoa(i), dir, depth+1, out)
+	}
+}
+
+// omitted reports whether a property may be left out when travelling in
+// dir, even when it is required.
+func (s *Schema) omitted(dir Direction) bool {
+	if s == nil {
+		return false
+	}
+	if dir == DirectionRequest {
+		return s.ReadOnly
+	}
+	return s.WriteOnly
+}
+
+// escapePointer escapes a property name for a JSON pointer (RFC 6901).
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
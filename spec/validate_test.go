@@ -0,0 +1,70 @@
+package spec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const accountSchema = `
+type: object
+required: [id, name, password]
+properties:
+  id: {type: integer, readOnly: true}
+  name: {type: string}
+  password: {type: string, writeOnly: true}
+`
+
+func mustSchema(t *testing.T, src string) *Schema {
+	t.Helper()
+	var s Schema
+	if err := yaml.Unmarshal([]byte(src), &s); err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+	return &s
+}
+
+func TestValidateRequiredByDirection(t *testing.T) {
+	s := mustSchema(t, accountSchema)
+	tests := []struct {
+		name string
+		body string
+		dir  Direction
+		want []string
+	}{
+		{"request without readOnly", `{"name":"a","password":"p"}`, DirectionRequest, nil},
+		{"request without writeOnly", `{"name":"a"}`, DirectionRequest, []string{`missing required property "password"`}},
+		{"response without writeOnly", `{"id":1,"name":"a"}`, DirectionResponse, nil},
+		{"response without readOnly", `{"name":"a"}`, DirectionResponse, []string{`missing required property "id"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v any
+			if err := json.Unmarshal([]byte(tt.body), &v); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, violation := range s.Validate(v, tt.dir) {
+				got = append(got, violation.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Validate()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSkeletonValidatesAsRequest(t *testing.T) {
+	s := mustSchema(t, accountSchema)
+	for _, full := range []bool{false, true} {
+		if got := s.Validate(s.Skeleton(SkeletonOptions{Full: full}), DirectionRequest); len(got) > 0 {
+			t.Errorf("skeleton (full=%v) has violations: %v", full, got)
+		}
+	}
+}
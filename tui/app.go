@@ -0,0 +1,730 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/atolix/clyst/auth"
+	"github.com/atolix/clyst/history"
+	"github.com/atolix/clyst/params"
+	"github.com/atolix/clyst/request"
+	"github.com/atolix/clyst/spec"
+	"github.com/atolix/clyst/theme"
+	"github.com/atolix/clyst/tui/selector"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Backend does the work behind the screens that needs the config, the
+// credential store and the history. main implements it.
+type Backend interface {
+	// LoadSpec loads the spec at source.
+	LoadSpec(source string) (*spec.OpenApiSpec, error)
+	// Servers lists the servers to offer for ep in the environment.
+	Servers(ep request.Endpoint, environment string) []spec.Server
+	// Authorizer returns the credentials stored for the spec. When the
+	// store cannot be read, it returns the error with an authorizer that
+	// keeps credentials for this session only.
+	Authorizer(doc *spec.OpenApiSpec) (*auth.Authorizer, error)
+	// Prepare fills in the environment of req as Send does.
+	Prepare(req Request) request.InputProvider
+	// Send sends the request and records it in the history. Canceling ctx
+	// abandons it, including a login it waits on. warning reports what went
+	// wrong besides the send, such as failing to record it.
+	Send(ctx context.Context, doc *spec.OpenApiSpec, req Request) (result request.ResultInfo, warning, err error)
+	// History lists the history of the spec, newest first.
+	History(doc *spec.OpenApiSpec) ([]history.Entry, error)
+}
+
+// Request is a request to send: the values as entered, before the
+// environment's variables are filled in, and where they go.
+type Request struct {
+	Environment string
+	Endpoint    request.Endpoint
+	Server      spec.Server
+	Input       request.InputProvider
+}
+
+// AppOptions configures RunApp.
+type AppOptions struct {
+	// Specs are the spec sources to choose from; a single one is opened
+	// right away.
+	Specs []string
+	// Environments can be switched with Ctrl+e, starting from Environment.
+	Environments []string
+	Environment  string
+	// ExitAfterSend ends the app with the first response instead of
+	// showing it, for output modes that print it.
+	ExitAfterSend bool
+	// Output is where the screens are drawn; nil means stdout.
+	Output io.Writer
+}
+
+// AppResult is how the app ended. Last is the last response received,
+// which the caller prints so it stays in the terminal. Err ended the app,
+// e.g. a spec that could not be loaded. Warning is a problem the app had
+// no time to show, as it ended after sending.
+type AppResult struct {
+	Last    *request.ResultInfo
+	Err     error
+	Warning error
+}
+
+type screen int
+
+const (
+	screenLoading screen = iota
+	screenSpecs
+	screenEndpoints
+	screenServers
+	screenPresets
+	screenForm
+	screenCredentials
+	screenResponse
+	screenHistory
+)
+
+type specLoadedMsg struct {
+	source string
+	doc    *spec.OpenApiSpec
+	err    error
+}
+
+type sentMsg struct {
+	req     Request
+	result  request.ResultInfo
+	err     error
+	warning error
+	// record saves the values as a preset when the form asked for it.
+	record bool
+}
+
+// loginMsg carries the authorization URL an OAuth2 login waits on; next
+// waits for another one.
+type loginMsg struct {
+	url  string
+	next tea.Cmd
+}
+
+// appModel is the root model. It owns one model per screen and moves
+// between them on the results the screens send, so going back finds a
+// screen as it was left.
+type appModel struct {
+	backend     Backend
+	opts        AppOptions
+	environment string
+
+	screen        screen
+	width, height int
+
+	specs       selector.Specs
+	endpoints   selector.Endpoints
+	servers     selector.Servers
+	presets     selector.Presets
+	form        paramFormModel
+	credentials authFormModel
+	response    responseModel
+	history     selector.History
+
+	source string
+	doc    *spec.OpenApiSpec
+	authz  *auth.Authorizer
+	ep     request.Endpoint
+	server spec.Server
+	// formBack is the screen the form goes back to.
+	formBack screen
+	// pending waits for credentials before it is sent, unless
+	// editCredentials is set: the credentials were opened from
+	// credentialsBack to change them.
+	pending         Request
+	editCredentials bool
+	credentialsBack screen
+	// lastReq is sent again from the response view; canEdit is set when it
+	// came from the form.
+	lastReq Request
+	canEdit bool
+	sending bool
+	// cancelSend abandons the request being sent; login is the
+	// authorization URL it waits on, if any.
+	cancelSend context.CancelFunc
+	login      string
+
+	status    string
+	statusErr bool
+	last      *request.ResultInfo
+	err       error
+	warning   error
+}
+
+// RunApp runs the interactive session: spec, endpoint, server, preset and
+// form screens, then the response, until the user quits.
+func RunApp(backend Backend, opts AppOptions) (AppResult, error) {
+	a := appModel{backend: backend, opts: opts, environment: opts.Environment}
+	if len(opts.Specs) > 1 {
+		items := make([]selector.SpecItem, 0, len(opts.Specs))
+		for _, p := range opts.Specs {
+			items = append(items, selector.SpecItem{TitleText: p, DescText: filepath.Dir(p), Value: p})
+		}
+		a.specs = selector.NewSpecs("Select an OpenAPI spec", items)
+		a.screen = screenSpecs
+	}
+
+	programOpts := []tea.ProgramOption{tea.WithAltScreen()}
+	if opts.Output != nil {
+		programOpts = append(programOpts, tea.WithOutput(opts.Output))
+		// Styles use the colors of the terminal they are drawn on, not
+		// those of stdout.
+		prev := lipgloss.ColorProfile()
+		lipgloss.SetColorProfile(lipgloss.NewRenderer(opts.Output).ColorProfile())
+		defer lipgloss.SetColorProfile(prev)
+	}
+	final, err := tea.NewProgram(a, programOpts...).Run()
+	if err != nil {
+		return AppResult{}, err
+	}
+	fa := final.(appModel)
+	return AppResult{Last: fa.last, Err: fa.err, Warning: fa.warning}, nil
+}
+
+func (a appModel) Init() tea.Cmd {
+	if len(a.opts.Specs) == 1 {
+		return a.loadSpec(a.opts.Specs[0])
+	}
+	return nil
+}
+
+func (a appModel) loadSpec(source string) tea.Cmd {
+	return func() tea.Msg {
+		doc, err := a.backend.LoadSpec(source)
+		return specLoadedMsg{source: source, doc: doc, err: err}
+	}
+}
+
+func (a appModel) send(ctx context.Context, req Request, record bool) tea.Cmd {
+	doc := a.doc
+	return func() tea.Msg {
+		result, warning, err := a.backend.Send(ctx, doc, req)
+		return sentMsg{req: req, result: result, err: err, warning: warning, record: record}
+	}
+}
+
+// waitLogin reports the authorization URLs a send prompts with, until ctx
+// ends.
+func waitLogin(ctx context.Context, urls <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case u := <-urls:
+			return loginMsg{url: u, next: waitLogin(ctx, urls)}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		a.width, a.height = msg.Width, msg.Height
+		return a, a.updateScreen(a.screenSize())
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			if a.cancelSend != nil {
+				a.cancelSend()
+			}
+			return a, tea.Quit
+		case "ctrl+e":
+			if a.screen != screenForm && a.screen != screenCredentials && len(a.opts.Environments) > 0 {
+				a.nextEnvironment()
+				return a, nil
+			}
+		}
+		if a.sending {
+			if msg.String() == "esc" {
+				a.cancelSend()
+				a.setStatus("Canceling…", false)
+			}
+			return a, nil
+		}
+		a.status = ""
+	case specLoadedMsg:
+		return a.specLoaded(msg)
+	case sentMsg:
+		return a.sent(msg)
+	case loginMsg:
+		if !a.sending {
+			return a, nil
+		}
+		a.login = msg.url
+		return a, msg.next
+	case selector.SpecResult:
+		a.setStatus("Loading "+msg.Value+"…", false)
+		return a, a.loadSpec(msg.Value)
+	case selector.EndpointResult:
+		return a.endpointDone(msg)
+	case selector.ServerResult:
+		switch {
+		case msg.Canceled:
+			return a, tea.Quit
+		case msg.Reselect || msg.Server == nil:
+			return a, a.show(screenEndpoints)
+		}
+		return a, a.chooseServer(*msg.Server, screenServers)
+	case selector.PresetSelection:
+		switch {
+		case msg.Canceled:
+			return a, tea.Quit
+		case msg.Reselect:
+			return a, a.show(screenEndpoints)
+		}
+		return a, a.openForm(initialValues(msg), screenPresets)
+	case formResult:
+		switch {
+		case msg.canceled:
+			return a, tea.Quit
+		case msg.back:
+			return a, a.show(a.formBack)
+		case msg.save:
+			return a, a.savePreset(msg.provider)
+		case msg.credentials:
+			return a, a.changeCredentials(screenForm)
+		}
+		return a, a.submit(msg.provider)
+	case credentialsResult:
+		return a.credentialsDone(msg)
+	case responseAction:
+		switch msg {
+		case responseResend:
+			return a, a.startSend(a.lastReq, false)
+		case responseEdit:
+			return a, a.show(screenForm)
+		case responseEndpoints:
+			return a, a.show(screenEndpoints)
+		case responseCredentials:
+			return a, a.changeCredentials(screenResponse)
+		}
+		return a, tea.Quit
+	case selector.HistoryResult:
+		return a.historyDone(msg)
+	}
+
+	return a, a.updateScreen(msg)
+}
+
+func (a appModel) specLoaded(msg specLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		err := fmt.Errorf("load %s: %w", msg.source, msg.err)
+		if len(a.opts.Specs) <= 1 {
+			a.err = err
+			return a, tea.Quit
+		}
+		a.setStatus(err.Error(), true)
+		return a, nil
+	}
+	a.source, a.doc = msg.source, msg.doc
+	a.endpoints = selector.NewEndpoints(selector.EndpointItems(msg.doc))
+	a.status = ""
+	if msg.doc.Stale {
+		a.setStatus("Could not reach "+msg.source+"; using the cached copy.", true)
+	}
+	var err error
+	if a.authz, err = a.backend.Authorizer(msg.doc); err != nil {
+		a.setStatus("failed to read credentials: "+err.Error(), true)
+	}
+	return a, a.show(screenEndpoints)
+}
+
+func (a appModel) endpointDone(msg selector.EndpointResult) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.SwitchSpecSelect:
+		if len(a.opts.Specs) > 1 {
+			return a, a.show(screenSpecs)
+		}
+		a.setStatus("No other spec to switch to", false)
+		return a, nil
+	case msg.ShowHistory:
+		entries, err := a.backend.History(a.doc)
+		if err != nil {
+			a.setStatus("failed to read history: "+err.Error(), true)
+		}
+		a.history = selector.NewHistory(a.doc.ID(), entries)
+		return a, a.show(screenHistory)
+	case msg.Selected == nil:
+		return a, nil
+	}
+
+	a.ep = request.Endpoint{
+		Method:    msg.Selected.Method,
+		Path:      msg.Selected.Path,
+		Operation: msg.Selected.Operation,
+	}
+	servers := a.backend.Servers(a.ep, a.environment)
+	switch len(servers) {
+	case 0:
+		a.setStatus("Not found server URL (define `servers` or `baseUrl` in the spec, or an environment `base_url`)", true)
+		return a, nil
+	case 1:
+		return a, a.chooseServer(servers[0], screenEndpoints)
+	}
+	a.servers = selector.NewServers(servers)
+	return a, a.show(screenServers)
+}
+
+// chooseServer continues with the preset list when the endpoint has
+// presets, or else with an empty form. back is the screen the form or
+// preset list returns to.
+func (a *appModel) chooseServer(server spec.Server, back screen) tea.Cmd {
+	a.server = server
+	store, err := params.Load(".", a.doc.ID())
+	if err != nil {
+		a.setStatus("failed to read saved params: "+err.Error(), true)
+	} else if len(store.PresetsFor(a.ep.Method, a.ep.Path)) > 0 {
+		a.presets = selector.NewPresets(a.ep, store)
+		return a.show(screenPresets)
+	}
+	return a.openForm(PrefilledProvider{}, back)
+}
+
+func (a *appModel) openForm(initial PrefilledProvider, back screen) tea.Cmd {
+	a.form = newParamFormModel(a.ep, a.server, initial)
+	backend, req := a.backend, Request{Environment: a.environment, Endpoint: a.ep, Server: a.server}
+	a.form.inEnv = func(input request.InputProvider) request.InputProvider {
+		req.Input = input
+		return backend.Prepare(req)
+	}
+	a.formBack = back
+	return a.show(screenForm)
+}
+
+// submit asks for missing credentials before sending.
+func (a *appModel) submit(provider PrefilledProvider) tea.Cmd {
+	req := Request{Environment: a.environment, Endpoint: a.ep, Server: a.server, Input: provider}
+	if missing := a.authz.Missing(a.ep); len(missing) > 0 {
+		a.pending, a.editCredentials = req, false
+		a.credentials = newAuthFormModel(missing, a.authz.Credential)
+		return a.show(screenCredentials)
+	}
+	a.canEdit = true
+	return a.startSend(req, true)
+}
+
+func (a appModel) credentialsDone(msg credentialsResult) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.canceled:
+		return a, tea.Quit
+	case msg.back && a.editCredentials:
+		return a, a.show(a.credentialsBack)
+	case msg.back:
+		return a, a.show(screenForm)
+	}
+	saved := true
+	for name, c := range msg.credentials {
+		if err := a.authz.Save(name, c); err != nil {
+			a.setStatus("failed to save credentials: "+err.Error(), true)
+			saved = false
+		}
+	}
+	if a.editCredentials {
+		if saved {
+			a.setStatus("Saved the credentials", false)
+		}
+		return a, a.show(a.credentialsBack)
+	}
+	a.canEdit = true
+	return a, a.startSend(a.pending, true)
+}
+
+// changeCredentials opens the stored credentials of the endpoint's
+// schemes for editing; saving or going back returns to back.
+func (a *appModel) changeCredentials(back screen) tea.Cmd {
+	schemes := a.authz.Schemes(a.ep)
+	if len(schemes) == 0 {
+		a.setStatus("This endpoint needs no credentials", false)
+		return nil
+	}
+	a.credentials = newAuthFormModel(schemes, a.authz.Credential)
+	a.editCredentials, a.credentialsBack = true, back
+	return a.show(screenCredentials)
+}
+
+func (a appModel) historyDone(msg selector.HistoryResult) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Canceled:
+		return a, tea.Quit
+	case msg.Reselect || msg.Resend == nil:
+		return a, a.show(screenEndpoints)
+	}
+
+	e := msg.Resend
+	_, op, _, ok := a.doc.FindOperation(e.Method, e.Path)
+	if !ok {
+		a.setStatus(fmt.Sprintf("%s %s is no longer in the spec", strings.ToUpper(e.Method), e.Path), true)
+		return a, nil
+	}
+	a.ep = request.Endpoint{Method: e.Method, Path: e.Path, Operation: op}
+	if len(e.Input.Secrets) > 0 {
+		// The history does not keep secret values; the form asks for them.
+		if slices.Contains(a.opts.Environments, e.Environment) {
+			a.environment = e.Environment
+		}
+		a.server = spec.Server{URL: e.Server}
+		input := e.Input
+		cmd := a.openForm(initialValues(selector.PresetSelection{Preset: &input}), screenHistory)
+		a.setStatus("Enter the secret values again to send", false)
+		return a, cmd
+	}
+	req := Request{
+		Environment: e.Environment,
+		Endpoint:    a.ep,
+		Server:      spec.Server{URL: e.Server},
+		Input:       request.PresetInput(e.Input),
+	}
+	a.canEdit = false
+	return a, a.startSend(req, false)
+}
+
+// startSend sends req in the background. An OAuth2 login it needs shows
+// its URL here, as the terminal belongs to the app, and Esc cancels it.
+func (a *appModel) startSend(req Request, record bool) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	urls := make(chan string)
+	ctx = auth.WithPrompt(ctx, func(u string) {
+		select {
+		case urls <- u:
+		case <-ctx.Done():
+		}
+	})
+	a.sending, a.cancelSend = true, cancel
+	a.setStatus(fmt.Sprintf("Sending %s %s…  Esc: cancel", strings.ToUpper(req.Endpoint.Method), req.Endpoint.Path), false)
+	return tea.Batch(a.send(ctx, req, record), waitLogin(ctx, urls))
+}
+
+func (a appModel) sent(msg sentMsg) (tea.Model, tea.Cmd) {
+	a.cancelSend()
+	a.sending, a.cancelSend, a.login = false, nil, ""
+	a.status = ""
+	if msg.err == nil {
+		a.last = &msg.result
+	}
+	if msg.warning != nil {
+		a.setStatus(msg.warning.Error(), true)
+	}
+	// A failed or canceled send still keeps the values the user recorded.
+	if msg.record && a.recordPreset(msg.req) && msg.err != nil {
+		a.setStatus("The request failed; the preset was saved", false)
+	}
+	if a.opts.ExitAfterSend {
+		a.warning = msg.warning
+		if msg.err != nil {
+			a.err = fmt.Errorf("sending request: %w", msg.err)
+		}
+		return a, tea.Quit
+	}
+	a.lastReq = msg.req
+	a.response = newResponseModel(msg.result, msg.err, a.canEdit)
+	return a, a.show(screenResponse)
+}
+
+// recordPreset saves the form's values when recording is on and reports
+// whether it saved them.
+func (a *appModel) recordPreset(req Request) bool {
+	provider, ok := req.Input.(PrefilledProvider)
+	if !ok || !provider.ShouldRecord() {
+		return false
+	}
+	if err := request.SavePreset(".", a.doc.ID(), req.Endpoint, provider); err != nil {
+		a.setStatus("failed to save params: "+err.Error(), true)
+		return false
+	}
+	a.form.saved()
+	if a.formBack == screenPresets {
+		// Show the saved preset when going back.
+		if store, err := params.Load(".", a.doc.ID()); err == nil {
+			a.presets = selector.NewPresets(a.ep, store)
+		}
+	}
+	return true
+}
+
+// savePreset saves the form's values without sending them, so a preset
+// can be edited while its server is down, and shows the preset list.
+func (a *appModel) savePreset(provider PrefilledProvider) tea.Cmd {
+	req := Request{Environment: a.environment, Endpoint: a.ep, Server: a.server, Input: provider}
+	if !a.recordPreset(req) {
+		return nil
+	}
+	store, err := params.Load(".", a.doc.ID())
+	if err != nil {
+		a.setStatus("failed to read saved params: "+err.Error(), true)
+		return nil
+	}
+	a.presets = selector.NewPresets(a.ep, store)
+	a.formBack = screenPresets
+	a.setStatus("Saved the preset", false)
+	return a.show(screenPresets)
+}
+
+// nextEnvironment cycles through the configured environments.
+func (a *appModel) nextEnvironment() {
+	next := 0
+	for i, name := range a.opts.Environments {
+		if name == a.environment {
+			next = (i + 1) % len(a.opts.Environments)
+			break
+		}
+	}
+	a.environment = a.opts.Environments[next]
+	a.setStatus("environment: "+a.environment, false)
+}
+
+func (a *appModel) setStatus(s string, isErr bool) {
+	a.status, a.statusErr = s, isErr
+}
+
+// show switches to s and gives it the current size.
+func (a *appModel) show(s screen) tea.Cmd {
+	a.screen = s
+	if a.width == 0 {
+		return nil
+	}
+	return a.updateScreen(a.screenSize())
+}
+
+// screenSize leaves a line for the status bar.
+func (a appModel) screenSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: a.width, Height: max(a.height-1, 0)}
+}
+
+func (a *appModel) updateScreen(msg tea.Msg) tea.Cmd {
+	var (
+		m   tea.Model
+		cmd tea.Cmd
+	)
+	switch a.screen {
+	case screenSpecs:
+		m, cmd = a.specs.Update(msg)
+		a.specs = m.(selector.Specs)
+	case screenEndpoints:
+		m, cmd = a.endpoints.Update(msg)
+		a.endpoints = m.(selector.Endpoints)
+	case screenServers:
+		m, cmd = a.servers.Update(msg)
+		a.servers = m.(selector.Servers)
+	case screenPresets:
+		m, cmd = a.presets.Update(msg)
+		a.presets = m.(selector.Presets)
+	case screenForm:
+		m, cmd = a.form.Update(msg)
+		a.form = m.(paramFormModel)
+	case screenCredentials:
+		m, cmd = a.credentials.Update(msg)
+		a.credentials = m.(authFormModel)
+	case screenResponse:
+		m, cmd = a.response.Update(msg)
+		a.response = m.(responseModel)
+	case screenHistory:
+		m, cmd = a.history.Update(msg)
+		a.history = m.(selector.History)
+	}
+	return cmd
+}
+
+func (a appModel) View() string {
+	var body string
+	switch a.screen {
+	case screenLoading:
+		body = "Loading…"
+	case screenSpecs:
+		body = a.specs.View()
+	case screenEndpoints:
+		body = a.endpoints.View()
+	case screenServers:
+		body = a.servers.View()
+	case screenPresets:
+		body = a.presets.View()
+	case screenForm:
+		body = a.form.View()
+	case screenCredentials:
+		body = a.credentials.View()
+	case screenResponse:
+		body = a.response.View()
+	case screenHistory:
+		body = a.history.View()
+	}
+	if a.login != "" {
+		body = a.loginView()
+	}
+	if a.height > 0 {
+		body = lipgloss.NewStyle().Height(a.height - 1).MaxHeight(a.height - 1).Render(body)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, body, a.statusBar())
+}
+
+// loginView asks the user to authorize clyst while a login waits for its
+// redirect.
+func (a appModel) loginView() string {
+	// The URL is cut at the screen width rather than at word breaks, so it
+	// can be copied in one piece.
+	url := a.login
+	if w := a.width - 4; w > 0 {
+		var lines []string
+		for len(url) > w {
+			lines = append(lines, url[:w])
+			url = url[w:]
+		}
+		url = strings.Join(append(lines, url), "\n")
+	}
+	return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join([]string{
+		lipgloss.NewStyle().Bold(true).Foreground(theme.Primary).Render("Authorize clyst"),
+		"",
+		"Open this URL in a browser on this machine:",
+		"",
+		lipgloss.NewStyle().Foreground(theme.Text).Render(url),
+		"",
+		lipgloss.NewStyle().Foreground(theme.Muted).Render("Waiting for the redirect…  Esc: cancel"),
+	}, "\n"))
+}
+
+// statusBar shows where the session is and the last status message.
+func (a appModel) statusBar() string {
+	bar := lipgloss.NewStyle().Foreground(theme.Text).Background(lipgloss.Color("#2b2d3a"))
+	name := bar.Bold(true).Foreground(theme.DarkText).Background(theme.Primary).Padding(0, 1)
+
+	parts := []string{"clyst"}
+	if a.source != "" {
+		parts = append(parts, a.source)
+	}
+	if a.environment != "" {
+		parts = append(parts, "env: "+a.environment)
+	}
+	if a.ep.Method != "" && a.screen != screenSpecs && a.screen != screenEndpoints {
+		parts = append(parts, strings.ToUpper(a.ep.Method)+" "+a.ep.Path)
+	}
+	left := name.Render(parts[0]) + bar.Render(" "+strings.Join(parts[1:], "  │  "))
+
+	right := "Ctrl+c: quit"
+	if len(a.opts.Environments) > 0 && a.screen != screenForm && a.screen != screenCredentials {
+		right = "Ctrl+e: environment  " + right
+	}
+	msgStyle := bar.Faint(true)
+	if a.status != "" {
+		right = a.status
+		msgStyle = bar
+		if a.statusErr {
+			msgStyle = bar.Foreground(lipgloss.Color("#ff6b6b"))
+		}
+	}
+	gap := max(a.width-lipgloss.Width(left)-lipgloss.Width(right)-1, 1)
+	return left + bar.Render(strings.Repeat(" ", gap)) + msgStyle.Render(right+" ")
+}
+
+// finish reports a screen's result to the app, which decides where to go
+// next.
+func finish(result tea.Msg) tea.Cmd {
+	return func() tea.Msg { return result }
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atolix/clyst/auth"
+	"github.com/atolix/clyst/theme"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type credentialField struct {
+	scheme   string
+	key      string
+	label    string
+	optional bool
+	input    textinput.Model
+	err      string
+}
+
+type authFormModel struct {
+	schemes      []auth.Scheme
+	fields       []credentialField
+	focusedIndex int
+	width        int
+}
+
+// credentialsResult is sent when the credentials form is saved or left.
+type credentialsResult struct {
+	credentials map[string]auth.Credential
+	canceled    bool
+	back        bool
+}
+
+// newAuthFormModel asks for the credentials of schemes, starting from
+// what stored holds for each of them.
+func newAuthFormModel(schemes []auth.Scheme, stored func(scheme string) auth.Credential) authFormModel {
+	var fields []credentialField
+	for _, s := range schemes {
+		cred := stored(s.Name)
+		for _, f := range auth.Fields(s.Scheme) {
+			ti := textinput.New()
+			ti.Prompt = "> "
+			ti.Placeholder = f.Label
+			if f.Secret {
+				ti.EchoMode = textinput.EchoPassword
+			}
+			ti.SetValue(cred.Get(f.Key))
+			fields = append(fields, credentialField{scheme: s.Name, key: f.Key, label: f.Label, optional: f.Optional, input: ti})
+		}
+	}
+	if len(fields) > 0 {
+		fields[0].input.Focus()
+	}
+
+	return authFormModel{schemes: schemes, fields: fields}
+}
+
+func (m authFormModel) Init() tea.Cmd { return nil }
+
+func (m authFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		for i := range m.fields {
+			m.fields[i].input.Width = m.width - 8
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+s":
+			return m.save()
+		case "ctrl+b":
+			return m, finish(credentialsResult{back: true})
+		case "esc":
+			return m, finish(credentialsResult{canceled: true})
+		case "enter":
+			if m.focusedIndex == len(m.fields)-1 {
+				return m.save()
+			}
+			m.move(1)
+			return m, nil
+		case "tab", "down":
+			m.move(1)
+			return m, nil
+		case "shift+tab", "up":
+			m.move(-1)
+			return m, nil
+		}
+	}
+
+	if len(m.fields) == 0 {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.fields[m.focusedIndex].input, cmd = m.fields[m.focusedIndex].input.Update(msg)
+	return m, cmd
+}
+
+// save finishes the form once every required field is filled in, or else
+// marks the empty ones.
+func (m authFormModel) save() (tea.Model, tea.Cmd) {
+	first := -1
+	for i, f := range m.fields {
+		m.fields[i].err = ""
+		if !f.optional && strings.TrimSpace(f.input.Value()) == "" {
+			m.fields[i].err = "is required"
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first >= 0 {
+		m.move(first - m.focusedIndex)
+		return m, nil
+	}
+	return m, finish(credentialsResult{credentials: m.credentials()})
+}
+
+func (m *authFormModel) move(delta int) {
+	if len(m.fields) == 0 {
+		return
+	}
+	m.fields[m.focusedIndex].input.Blur()
+	m.focusedIndex = (m.focusedIndex + delta + len(m.fields)) % len(m.fields)
+	m.fields[m.focusedIndex].input.Focus()
+}
+
+func (m authFormModel) View() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(theme.Primary).Render("Credentials")
+	section := lipgloss.NewStyle().Bold(true)
+	label := lipgloss.NewStyle().Foreground(theme.Muted)
+	box := lipgloss.NewStyle().Width(max(m.width-5, 0)).Border(lipgloss.RoundedBorder()).BorderForeground(theme.Border).Padding(1, 2)
+
+	hints := lipgloss.NewStyle().Faint(true).Render(strings.Join([]string{
+		"Tab/Shift+Tab: move",
+		"Enter: next / save",
+		"Ctrl+s: save",
+		"Ctrl+b: back",
+		"Esc: cancel",
+	}, "  "))
+	sections := []string{hints, "", label.Render("Stored per spec in your user config directory, not in presets.")}
+
+	for _, s := range m.schemes {
+		sections = append(sections, "", section.Render(fmt.Sprintf("%s — %s", s.Name, s.Scheme.Label())))
+		for _, f := range m.fields {
+			if f.scheme == s.Name {
+				view := label.Render(f.label) + "\n" + f.input.View()
+				if f.err != "" {
+					view += "\n" + errorStyle.Render(f.err)
+				}
+				sections = append(sections, view)
+			}
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, title, box.Render(lipgloss.JoinVertical(lipgloss.Left, sections...)))
+}
+
+func (m authFormModel) credentials() map[string]auth.Credential {
+	out := map[string]auth.Credential{}
+	for _, f := range m.fields {
+		c := out[f.scheme]
+		c.Set(f.key, strings.TrimSpace(f.input.Value()))
+		out[f.scheme] = c
+	}
+	return out
+}
@@ -0,0 +1,621 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/atolix/clyst/spec"
+	"github.com/atolix/clyst/theme"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Node kinds besides the JSON Schema types.
+const (
+	// nodeEnum picks one of the schema's enum values.
+	nodeEnum = "enum"
+	// nodeRaw holds JSON text, for values the schema does not describe or
+	// that do not match it.
+	nodeRaw = "raw"
+)
+
+// bodyNode is one value of the body tree.
+type bodyNode struct {
+	// key is the property name; array items and the root have none.
+	key    string
+	schema *spec.Schema
+	kind   string
+	// set is false for optional properties left out of the body.
+	set      bool
+	children []*bodyNode
+	parent   *bodyNode
+	// text holds scalars as typed, and raw values as JSON.
+	text string
+	enum []any
+	// choice is the index of the picked enum value, -1 for none.
+	choice    int
+	collapsed bool
+}
+
+// newBodyNode builds the node for v, a value decoded with UseNumber, as
+// described by schema. set is false for a property missing from its object.
+func newBodyNode(key string, schema *spec.Schema, v any, set bool, parent *bodyNode) *bodyNode {
+	flat := schema.Flatten(v)
+	n := &bodyNode{key: key, schema: flat, set: set, parent: parent}
+
+	if flat != nil && len(flat.Enum) > 0 {
+		n.kind, n.enum, n.choice = nodeEnum, flat.Enum, -1
+		if v == nil {
+			return n
+		}
+		if i := slices.IndexFunc(flat.Enum, func(e any) bool { return sameJSON(e, v) }); i >= 0 {
+			n.choice = i
+			return n
+		}
+	}
+
+	kind := flat.PrimaryType()
+	if v != nil || set {
+		// Keep values that do not match the schema, null included, as JSON.
+		vk := spec.JSONKind(v)
+		switch {
+		case kind == "":
+			kind = vk
+		case kind != vk && !(kind == "number" && vk == "integer"):
+			kind = nodeRaw
+		}
+	}
+	if kind == "" {
+		kind = nodeRaw
+	}
+	n.kind = kind
+
+	switch kind {
+	case "object":
+		obj, _ := v.(map[string]any)
+		var props []string
+		if flat != nil {
+			props = slices.Sorted(maps.Keys(flat.Properties))
+		}
+		for _, name := range props {
+			prop := flat.Properties[name]
+			pv, ok := obj[name]
+			if prop != nil && prop.ReadOnly && !ok {
+				continue
+			}
+			n.children = append(n.children, newBodyNode(name, prop, pv, ok, n))
+		}
+		for _, name := range slices.Sorted(maps.Keys(obj)) {
+			if !slices.Contains(props, name) {
+				var extra *spec.Schema
+				if flat != nil {
+					extra = flat.AdditionalProperties
+				}
+				n.children = append(n.children, newBodyNode(name, extra, obj[name], true, n))
+			}
+		}
+	case "array":
+		items, _ := v.([]any)
+		for i, item := range items {
+			n.children = append(n.children, newBodyNode("", itemSchema(flat, i), item, true, n))
+		}
+	case "string":
+		n.text, _ = v.(string)
+	case "boolean":
+		n.text = strconv.FormatBool(v == true)
+	case "null":
+		n.text = "null"
+	default:
+		if v != nil || set {
+			b, _ := json.Marshal(v)
+			n.text = string(b)
+		}
+	}
+	return n
+}
+
+// itemSchema is the schema of the i-th item of an array.
+func itemSchema(array *spec.Schema, i int) *spec.Schema {
+	switch {
+	case array == nil:
+		return nil
+	case i < len(array.PrefixItems):
+		return array.PrefixItems[i]
+	}
+	return array.Items
+}
+
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// value turns the node back into a JSON value. Raw text that is not JSON
+// is kept as a string, so nothing typed is lost. A node without a value
+// (see absent) is left out of its object and null elsewhere.
+func (n *bodyNode) value() any {
+	switch n.kind {
+	case "object":
+		obj := map[string]any{}
+		for _, c := range n.children {
+			if c.set && !c.absent() {
+				obj[c.key] = c.value()
+			}
+		}
+		return obj
+	case "array":
+		items := make([]any, 0, len(n.children))
+		for _, c := range n.children {
+			items = append(items, c.value())
+		}
+		return items
+	case nodeEnum:
+		if n.choice < 0 {
+			return nil
+		}
+		return n.enum[n.choice]
+	case "string":
+		return n.text
+	case "boolean":
+		return n.text == "true"
+	case "null":
+		return nil
+	case "integer", "number":
+		if num, ok := n.number(); ok {
+			return num
+		}
+		return nil
+	}
+	if strings.TrimSpace(n.text) == "" {
+		return nil
+	}
+	if json.Valid([]byte(n.text)) {
+		return json.RawMessage(n.text)
+	}
+	return n.text
+}
+
+// number parses the text of a number node.
+func (n *bodyNode) number() (json.Number, bool) {
+	v, err := decodeJSON(n.text)
+	num, ok := v.(json.Number)
+	return num, err == nil && ok
+}
+
+// absent reports whether the node has no value: an enum without a choice,
+// or a number that is empty or does not parse (see bodyTree.Invalid).
+func (n *bodyNode) absent() bool {
+	switch n.kind {
+	case nodeEnum:
+		return n.choice < 0
+	case "integer", "number":
+		_, ok := n.number()
+		return !ok
+	}
+	return false
+}
+
+// editable reports whether the node's value is typed in.
+func (n *bodyNode) editable() bool {
+	switch n.kind {
+	case "string", "integer", "number", nodeRaw:
+		return n.set
+	}
+	return false
+}
+
+// required reports whether the node's object lists it as required.
+func (n *bodyNode) required() bool {
+	p := n.parent
+	return p == nil || p.kind != "object" || (p.schema != nil && slices.Contains(p.schema.Required, n.key))
+}
+
+// fill sets an unset node, starting from the skeleton of its schema.
+func (n *bodyNode) fill() *bodyNode {
+	v, _ := decodeJSON(skeletonJSON(n.schema))
+	filled := newBodyNode(n.key, n.schema, v, true, n.parent)
+	if n.parent != nil {
+		n.parent.children[slices.Index(n.parent.children, n)] = filled
+	}
+	return filled
+}
+
+func skeletonJSON(schema *spec.Schema) string {
+	b, err := json.Marshal(schema.Skeleton(spec.SkeletonOptions{}))
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+func decodeJSON(text string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+type bodyRow struct {
+	node  *bodyNode
+	depth int
+}
+
+// bodyTree edits the body as a tree of typed fields laid out by the body
+// schema. Text is typed into the focused row; booleans, enums, optional
+// properties and array items are changed with keys.
+type bodyTree struct {
+	root    *bodyNode
+	rows    []bodyRow
+	cursor  int
+	offset  int
+	input   textinput.Model
+	focused bool
+	width   int
+	height  int
+	// errors are the problems found by validation, by JSON pointer.
+	errors map[string]string
+}
+
+// newBodyTree lays out raw, the body text, along schema. An empty body
+// starts from the schema's skeleton.
+func newBodyTree(schema *spec.Schema, raw string) (bodyTree, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = skeletonJSON(schema)
+	}
+	v, err := decodeJSON(raw)
+	if err != nil {
+		return bodyTree{}, err
+	}
+	t := bodyTree{root: newBodyNode("", schema, v, true, nil), input: textinput.New(), height: 10}
+	t.input.Prompt = ""
+	t.refresh()
+	return t, nil
+}
+
+// JSON renders the tree as the body text.
+func (t bodyTree) JSON() string {
+	t.commit()
+	b, err := json.MarshalIndent(t.root.value(), "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Invalid returns the numbers typed in that do not parse, by JSON pointer.
+// They are left out of the JSON, so the form reports them instead.
+func (t bodyTree) Invalid() map[string]string {
+	t.commit()
+	out := map[string]string{}
+	var walk func(n *bodyNode)
+	walk = func(n *bodyNode) {
+		if !n.set {
+			return
+		}
+		if (n.kind == "integer" || n.kind == "number") && strings.TrimSpace(n.text) != "" && n.absent() {
+			out[n.pointer()] = "is not a number"
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	if t.root != nil {
+		walk(t.root)
+	}
+	return out
+}
+
+// SetErrors shows the problems next to their rows, keyed by JSON pointer.
+func (t *bodyTree) SetErrors(errors map[string]string) {
+	t.errors = errors
+}
+
+func (t *bodyTree) SetSize(width, height int) {
+	t.width, t.height = width, max(height, 3)
+	t.input.Width = max(width/2, 10)
+	t.scroll()
+}
+
+func (t *bodyTree) Focus() {
+	t.focused = true
+	t.load()
+}
+
+func (t *bodyTree) Blur() {
+	t.commit()
+	t.focused = false
+	t.input.Blur()
+}
+
+func (t bodyTree) current() *bodyNode {
+	if t.cursor < len(t.rows) {
+		return t.rows[t.cursor].node
+	}
+	return nil
+}
+
+// refresh lists the visible rows after the tree changed.
+func (t *bodyTree) refresh() {
+	t.rows = t.rows[:0]
+	var walk func(n *bodyNode, depth int)
+	walk = func(n *bodyNode, depth int) {
+		t.rows = append(t.rows, bodyRow{node: n, depth: depth})
+		if !n.set || n.collapsed {
+			return
+		}
+		for _, c := range n.children {
+			walk(c, depth+1)
+		}
+	}
+	walk(t.root, 0)
+	t.cursor = min(t.cursor, len(t.rows)-1)
+	t.scroll()
+	t.load()
+}
+
+// commit stores the typed text in the current node.
+func (t *bodyTree) commit() {
+	if n := t.current(); n != nil && n.editable() {
+		n.text = t.input.Value()
+	}
+}
+
+// load puts the current node's text in the input.
+func (t *bodyTree) load() {
+	n := t.current()
+	if n == nil || !n.editable() || !t.focused {
+		t.input.Blur()
+		return
+	}
+	t.input.SetValue(n.text)
+	t.input.CursorEnd()
+	t.input.Focus()
+}
+
+func (t *bodyTree) move(delta int) {
+	t.commit()
+	t.cursor += delta
+	t.scroll()
+	t.load()
+}
+
+func (t *bodyTree) scroll() {
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+t.height {
+		t.offset = t.cursor - t.height + 1
+	}
+}
+
+// Update handles a key while the tree has the focus. handled is false for
+// up at the first row and down at the last, which leave the tree.
+func (t bodyTree) Update(msg tea.Msg) (bodyTree, tea.Cmd, bool) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return t, nil, false
+	}
+	n := t.current()
+	if n == nil {
+		return t, nil, false
+	}
+
+	switch key.String() {
+	case "up":
+		if t.cursor == 0 {
+			return t, nil, false
+		}
+		t.move(-1)
+		return t, nil, true
+	case "down":
+		if t.cursor == len(t.rows)-1 {
+			return t, nil, false
+		}
+		t.move(1)
+		return t, nil, true
+	case "ctrl+n":
+		t.add(n)
+		return t, nil, true
+	case "ctrl+x":
+		t.remove(n)
+		return t, nil, true
+	}
+
+	if !n.editable() {
+		switch key.String() {
+		case " ", "right":
+			t.change(n, 1)
+		case "left":
+			t.change(n, -1)
+		}
+		return t, nil, true
+	}
+
+	var cmd tea.Cmd
+	t.input, cmd = t.input.Update(msg)
+	return t, cmd, true
+}
+
+// change toggles booleans, steps through enums, folds containers and sets
+// unset properties.
+func (t *bodyTree) change(n *bodyNode, step int) {
+	switch {
+	case !n.set:
+		n.fill()
+	case n.kind == "boolean":
+		n.text = strconv.FormatBool(n.text != "true")
+	case n.kind == nodeEnum:
+		// Step through the values and "no choice" (-1).
+		states := len(n.enum) + 1
+		n.choice = (n.choice+1+step+states)%states - 1
+	case n.kind == "object" || n.kind == "array":
+		n.collapsed = step < 0
+	}
+	t.refresh()
+}
+
+// add sets an unset property, or adds an array item after the focused one
+// or at the end of the focused array.
+func (t *bodyTree) add(n *bodyNode) {
+	t.commit()
+	switch {
+	case !n.set:
+		n.fill()
+	case n.kind == "array":
+		n.collapsed = false
+		item := &bodyNode{parent: n, schema: itemSchema(n.schema, len(n.children))}
+		n.children = append(n.children, item)
+		item = item.fill()
+		t.cursor = t.rowOf(n) + countRows(n) - countRows(item)
+	case n.parent != nil && n.parent.kind == "array":
+		p := n.parent
+		i := slices.Index(p.children, n) + 1
+		item := &bodyNode{parent: p, schema: itemSchema(p.schema, i)}
+		p.children = slices.Insert(p.children, i, item)
+		item.fill()
+		t.cursor = t.rowOf(n) + countRows(n)
+	}
+	t.refresh()
+}
+
+// remove deletes an array item, or unsets an optional property.
+func (t *bodyTree) remove(n *bodyNode) {
+	p := n.parent
+	switch {
+	case p == nil:
+		return
+	case p.kind == "array":
+		p.children = slices.DeleteFunc(p.children, func(c *bodyNode) bool { return c == n })
+	case n.set && !n.required():
+		n.set = false
+		n.children = nil
+	default:
+		return
+	}
+	t.refresh()
+}
+
+func (t bodyTree) rowOf(n *bodyNode) int {
+	return slices.IndexFunc(t.rows, func(r bodyRow) bool { return r.node == n })
+}
+
+// countRows is the number of rows n takes when expanded.
+func countRows(n *bodyNode) int {
+	rows := 1
+	if n.set && !n.collapsed {
+		for _, c := range n.children {
+			rows += countRows(c)
+		}
+	}
+	return rows
+}
+
+func (t bodyTree) View() string {
+	muted := lipgloss.NewStyle().Foreground(theme.Muted)
+	faint := lipgloss.NewStyle().Faint(true)
+	cursor := lipgloss.NewStyle().Foreground(theme.Primary).Bold(true)
+
+	var lines []string
+	end := min(t.offset+t.height, len(t.rows))
+	for i := t.offset; i < end; i++ {
+		row := t.rows[i]
+		n := row.node
+		focused := t.focused && i == t.cursor
+
+		marker := "  "
+		if focused {
+			marker = cursor.Render("› ")
+		}
+		label := n.key
+		if n.parent != nil && n.parent.kind == "array" {
+			label = fmt.Sprintf("[%d]", slices.Index(n.parent.children, n))
+		} else if n.parent == nil {
+			label = "body"
+		}
+		if n.parent != nil && n.parent.kind == "object" && n.required() {
+			label += "*"
+		}
+		line := marker + strings.Repeat("  ", row.depth)
+
+		if !n.set {
+			lines = append(lines, line+faint.Render(label+" (unset, Space or Ctrl+n to add)"))
+			continue
+		}
+		switch n.kind {
+		case "object", "array":
+			fold := "▾ "
+			if n.collapsed {
+				fold = "▸ "
+			}
+			count := fmt.Sprintf("{%d}", len(n.children))
+			if n.kind == "array" {
+				count = fmt.Sprintf("[%d]", len(n.children))
+			}
+			line += fold + label + " " + muted.Render(count)
+		default:
+			line += label + " " + muted.Render(n.typeLabel()) + ": "
+			switch {
+			case focused && n.editable():
+				line += t.input.View()
+			case n.kind == "string":
+				line += strconv.Quote(n.text)
+			case n.kind == nodeEnum && n.choice < 0:
+				line += "‹ " + muted.Render("no choice") + " ›"
+			case n.kind == nodeEnum:
+				line += "‹ " + spec.FormatSample(n.enum[n.choice]) + " ›"
+			default:
+				line += n.text
+			}
+		}
+		if msg, ok := t.errors[n.pointer()]; ok {
+			line += "  " + errorStyle.Render(msg)
+		}
+		lines = append(lines, line)
+	}
+
+	hints := "Space/←/→: toggle, pick, fold  Ctrl+n/Ctrl+x: add/remove item or field  *: required"
+	if len(t.rows) > t.height {
+		hints = fmt.Sprintf("%d/%d  ", t.cursor+1, len(t.rows)) + hints
+	}
+	lines = append(lines, faint.Render(hints))
+	return strings.Join(lines, "\n")
+}
+
+// pointer is the JSON pointer of the node in the body.
+func (n *bodyNode) pointer() string {
+	if n.parent == nil {
+		return ""
+	}
+	key := strings.ReplaceAll(strings.ReplaceAll(n.key, "~", "~0"), "/", "~1")
+	if n.parent.kind == "array" {
+		key = strconv.Itoa(slices.Index(n.parent.children, n))
+	}
+	return n.parent.pointer() + "/" + key
+}
+
+func (n *bodyNode) typeLabel() string {
+	switch n.kind {
+	case nodeEnum:
+		return "(enum)"
+	case nodeRaw:
+		return "(JSON)"
+	}
+	if label := n.schema.TypeString(); label != "" {
+		return "(" + label + ")"
+	}
+	return "(" + n.kind + ")"
+}
@@ -2,6 +2,9 @@ package tui
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/atolix/clyst/params"
@@ -17,170 +20,315 @@ import (
 )
 
 type PrefilledProvider struct {
+	server    map[string]string
 	path      map[string]string
 	query     map[string]string
+	header    map[string]string
+	cookie    map[string]string
+	custom    map[string]string
 	body      string
+	name      string
 	recording bool
-	reselect  bool
+	// editID is the preset being edited when editing is set.
+	editID  string
+	editing bool
+	local   bool
+	// secrets lists the secret fields (see params.SecretRef). Without a
+	// preset it is nil and fields are marked by name instead.
+	secrets []string
 }
 
-type TUIInput struct {
-	Endpoint  request.Endpoint
-	collected bool
-	provider  PrefilledProvider
-	canceled  bool
-}
-
-type paramField struct {
-	p     spec.Parameter
-	input textinput.Model
-}
-
-type paramFormModel struct {
-	ep           request.Endpoint
-	pathFields   []paramField
-	queryFields  []paramField
-	bodyArea     textarea.Model
-	hasBody      bool
-	focusedIndex int
-	width        int
-	height       int
-	canceled     bool
-	recording    bool
-}
+const (
+	fieldServer = "server"
+	fieldPath   = "path"
+	fieldQuery  = "query"
+	fieldHeader = "header"
+	fieldCookie = "cookie"
 
-func (p PrefilledProvider) GetPathParam(param spec.Parameter) string  { return p.path[param.Name] }
-func (p PrefilledProvider) GetQueryParam(param spec.Parameter) string { return p.query[param.Name] }
-func (p PrefilledProvider) GetRequestBody() string                    { return p.body }
-func (p PrefilledProvider) ShouldRecord() bool                        { return p.recording }
-func (p PrefilledProvider) ShouldReselectEndpoint() bool              { return p.reselect }
+	// The preset name field is the first field while recording is on.
+	fieldPresetName = "preset-name"
 
-func CollectParams(ep request.Endpoint) (PrefilledProvider, bool, error) {
-	var initial PrefilledProvider
+	// Custom headers are rows of two adjacent fields: name, then value.
+	fieldCustomName  = "custom-name"
+	fieldCustomValue = "custom-value"
+	customNameWidth  = 24
 
-	if store, err := params.Load("."); err == nil {
-		if presets := store.PresetsFor(ep.Method, ep.Path); len(presets) > 0 {
-			selected, reselect, canceled, err := selector.SelectPreset(ep, presets)
-			if err != nil {
-				fmt.Println("failed to select preset:", err)
-			} else if reselect {
-				return PrefilledProvider{reselect: true}, true, nil
-			} else if canceled {
-				return PrefilledProvider{}, true, nil
-			} else if selected != nil {
-				initial.path = selected.Path
-				initial.query = selected.Query
-				initial.body = selected.Body
-			}
-		}
-	} else {
-		fmt.Println("failed to read saved params:", err)
-	}
+	// secretKindCustom is the params.SecretRef kind of custom headers.
+	secretKindCustom = "custom_headers"
+)
 
-	m := newParamFormModel(ep, initial)
-	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
-	if err != nil {
-		return PrefilledProvider{}, false, err
-	}
-	fm := final.(paramFormModel)
-	return fm.toProvider(), fm.canceled, nil
-}
+// maxBodyErrors is how many body problems are listed below the body.
+const maxBodyErrors = 5
 
-func (c *TUIInput) ensureCollected() {
-	if c.collected {
-		return
-	}
-	if p, canceled, err := CollectParams(c.Endpoint); err == nil {
-		c.provider = p
-		c.collected = true
-		c.canceled = canceled
-	}
-}
+var errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b"))
 
-func (c *TUIInput) GetPathParam(p spec.Parameter) string {
-	c.ensureCollected()
-	return c.provider.GetPathParam(p)
+var fieldSections = []struct {
+	kind  string
+	title string
+}{
+	{fieldServer, "Server Variables"},
+	{fieldPath, "Path Params"},
+	{fieldQuery, "Query Params"},
+	{fieldHeader, "Header Params"},
+	{fieldCookie, "Cookie Params"},
 }
 
-func (c *TUIInput) GetQueryParam(p spec.Parameter) string {
-	c.ensureCollected()
-	return c.provider.GetQueryParam(p)
+type paramField struct {
+	kind  string
+	name  string
+	label string
+	input textinput.Model
+	// secret fields are masked and kept out of the shared preset file. For
+	// custom headers it is set on the value field.
+	secret bool
+	// err describes why the value does not fit the spec.
+	err string
 }
 
-func (c *TUIInput) GetRequestBody() string {
-	c.ensureCollected()
-	return c.provider.GetRequestBody()
+// formResult is sent when the form is submitted or left.
+type formResult struct {
+	provider PrefilledProvider
+	canceled bool
+	// back asks for the previous screen.
+	back bool
+	// save asks to save the values as a preset without sending them.
+	save bool
+	// credentials asks to change the stored credentials.
+	credentials bool
 }
 
-func (c *TUIInput) ShouldRecord() bool {
-	c.ensureCollected()
-	return c.provider.ShouldRecord()
+// initialValues starts the form from a saved preset.
+func initialValues(choice selector.PresetSelection) PrefilledProvider {
+	var initial PrefilledProvider
+	if selected := choice.Preset; selected != nil {
+		initial.editID = choice.ID
+		initial.editing = choice.Edit
+		initial.path = selected.Path
+		initial.query = selected.Query
+		initial.header = selected.Header
+		initial.cookie = selected.Cookie
+		initial.custom = selected.CustomHeaders
+		initial.body = selected.Body
+		initial.name = selected.Name
+		initial.local = selected.Local
+		initial.secrets = append([]string{}, selected.Secrets...)
+	}
+	return initial
 }
 
-func (c *TUIInput) ShouldReselectEndpoint() bool {
-	c.ensureCollected()
-	return c.provider.ShouldReselectEndpoint()
+type paramFormModel struct {
+	ep           request.Endpoint
+	server       spec.Server
+	fields       []paramField
+	bodyArea     textarea.Model
+	hasBody      bool
+	focusedIndex int
+	width        int
+	height       int
+	recording    bool
+	presetName   string
+	editID       string
+	editing      bool
+	local        bool
+	bodySecret   bool
+	// skeleton is what Ctrl+g last generated the body from, once generated
+	// is set.
+	skeleton  spec.SkeletonOptions
+	generated bool
+	// treeMode edits the body in bodyTree instead of bodyArea.
+	treeMode bool
+	bodyTree bodyTree
+	bodyNote string
+	// checked is set once a submit was held back; from then on the values
+	// are checked as they are typed.
+	checked  bool
+	problems int
+	bodyErrs []request.FieldError
+	// inEnv fills in the environment as sending would, so a header the
+	// environment supplies counts as set.
+	inEnv func(request.InputProvider) request.InputProvider
 }
 
-func (c *TUIInput) Canceled() bool {
-	return c.canceled
+func (p PrefilledProvider) GetServerVariable(name string, _ spec.ServerVariable) string {
+	return p.server[name]
 }
 
-func newParamFormModel(ep request.Endpoint, initial PrefilledProvider) paramFormModel {
-	var pathFields []paramField
-	var queryFields []paramField
-	for _, p := range ep.Operation.Parameters {
+func (p PrefilledProvider) GetPathParam(param spec.Parameter) string   { return p.path[param.Name] }
+func (p PrefilledProvider) GetQueryParam(param spec.Parameter) string  { return p.query[param.Name] }
+func (p PrefilledProvider) GetHeaderParam(param spec.Parameter) string { return p.header[param.Name] }
+func (p PrefilledProvider) GetCookieParam(param spec.Parameter) string { return p.cookie[param.Name] }
+func (p PrefilledProvider) GetCustomHeaders() map[string]string        { return p.custom }
+func (p PrefilledProvider) GetRequestBody() string                     { return p.body }
+func (p PrefilledProvider) PresetName() string                         { return p.name }
+func (p PrefilledProvider) ShouldRecord() bool                         { return p.recording }
+func (p PrefilledProvider) EditingPreset() (string, bool)              { return p.editID, p.editing }
+func (p PrefilledProvider) PresetLocal() bool                          { return p.local }
+func (p PrefilledProvider) PresetSecrets() []string                    { return p.secrets }
+
+func newParamFormModel(ep request.Endpoint, server spec.Server, initial PrefilledProvider) paramFormModel {
+	var fields []paramField
+	for _, name := range server.VariableNames() {
+		v := server.Variables[name]
 		ti := textinput.New()
 		ti.Prompt = "> "
-		ti.Placeholder = fmt.Sprintf("%s (%s)", p.Name, p.Schema.Type)
-		switch p.In {
-		case "path":
-			if initial.path != nil {
-				if v, ok := initial.path[p.Name]; ok {
-					ti.SetValue(v)
-				}
+		ti.Placeholder = v.Default
+		if len(v.Enum) > 0 {
+			ti.Placeholder = fmt.Sprintf("%s (%s)", v.Default, strings.Join(v.Enum, "|"))
+		}
+		if val, ok := initial.server[name]; ok {
+			ti.SetValue(val)
+		}
+		fields = append(fields, paramField{kind: fieldServer, name: name, label: serverVariableLabel(name, v), input: ti})
+	}
+
+	for _, kind := range []string{fieldPath, fieldQuery, fieldHeader, fieldCookie} {
+		for _, p := range ep.Operation.Parameters {
+			if p.In != kind {
+				continue
 			}
-			pathFields = append(pathFields, paramField{p: p, input: ti})
-		case "query":
-			if initial.query != nil {
-				if v, ok := initial.query[p.Name]; ok {
-					ti.SetValue(v)
-				}
+			ti := textinput.New()
+			ti.Prompt = "> "
+			ti.Placeholder = paramPlaceholder(p)
+			if v, ok := initial.valuesFor(kind)[p.Name]; ok {
+				ti.SetValue(v)
 			}
-			queryFields = append(queryFields, paramField{p: p, input: ti})
+			label := fmt.Sprintf("%s (%s)", p.Name, p.Schema.TypeString())
+			field := paramField{kind: kind, name: p.Name, label: label, input: ti}
+			field.setSecret(initial.isSecret(kind, p.Name))
+			fields = append(fields, field)
 		}
 	}
 
+	names := make([]string, 0, len(initial.custom))
+	for name := range initial.custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		row := newCustomHeaderRow(name, initial.custom[name])
+		row[1].setSecret(initial.isSecret(secretKindCustom, name))
+		fields = append(fields, row...)
+	}
+
 	ta := textarea.New()
 	ta.Placeholder = "{\n  \"example\": \"value\"\n}"
 	ta.ShowLineNumbers = false
 	ta.MaxWidth = 0
+	hasBody := ep.Operation.RequestBody != nil
+	skeleton := ep.Operation.RequestBody.ExampleBody()
+	if skeleton == "" {
+		skeleton = ep.Operation.RequestBody.SkeletonBody(spec.SkeletonOptions{})
+	}
+	if skeleton != "" {
+		ta.Placeholder = skeleton
+		// A required body starts from the skeleton; an optional one only
+		// shows it, so leaving the body empty still sends none.
+		if ep.Operation.RequestBody.Required {
+			ta.SetValue(skeleton)
+		}
+	}
 	if strings.TrimSpace(initial.body) != "" {
 		ta.SetValue(initial.body)
 	}
-	hasBody := ep.Operation.RequestBody != nil
 
 	m := paramFormModel{
 		ep:           ep,
-		pathFields:   pathFields,
-		queryFields:  queryFields,
+		server:       server,
+		fields:       fields,
 		bodyArea:     ta,
 		hasBody:      hasBody,
 		focusedIndex: 0,
 		recording:    false,
+		presetName:   initial.name,
+		editID:       initial.editID,
+		editing:      initial.editing,
+		local:        initial.local,
+		bodySecret:   slices.Contains(initial.secrets, params.SecretBody),
 	}
 
-	if len(m.pathFields) > 0 {
-		m.pathFields[0].input.Focus()
-	} else if len(m.queryFields) > 0 {
-		m.queryFields[0].input.Focus()
+	if len(m.fields) > 0 {
+		m.fields[0].input.Focus()
 	} else if m.hasBody {
 		m.bodyArea.Focus()
 	}
+	// Editing saves back through recording; turning it off discards the edit.
+	if m.editing {
+		m.toggleRecording()
+	}
 
 	return m
 }
 
+func newCustomHeaderRow(name, value string) []paramField {
+	nameInput := textinput.New()
+	nameInput.Prompt = "> "
+	nameInput.Placeholder = "X-Header-Name"
+	nameInput.Width = customNameWidth
+	nameInput.SetValue(name)
+
+	valueInput := textinput.New()
+	valueInput.Prompt = ": "
+	valueInput.Placeholder = "value"
+	valueInput.SetValue(value)
+
+	return []paramField{
+		{kind: fieldCustomName, input: nameInput},
+		{kind: fieldCustomValue, input: valueInput},
+	}
+}
+
+func paramPlaceholder(p spec.Parameter) string {
+	placeholder := fmt.Sprintf("%s (%s)", p.Name, p.Schema.TypeString())
+	if p.Example != nil {
+		return placeholder + " e.g. " + spec.FormatSample(p.Example)
+	}
+	if v, ok := p.Schema.SampleValue(); ok {
+		return placeholder + " e.g. " + spec.FormatSample(v)
+	}
+	return placeholder
+}
+
+func serverVariableLabel(name string, v spec.ServerVariable) string {
+	if strings.TrimSpace(v.Description) == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, v.Description)
+}
+
+// isSecret reports whether the field starts out secret: as saved in the
+// preset, or by its name when there is no preset.
+func (p PrefilledProvider) isSecret(kind, name string) bool {
+	if p.secrets == nil {
+		return params.LooksSecret(name)
+	}
+	return slices.Contains(p.secrets, params.SecretRef(kind, name))
+}
+
+func (f *paramField) setSecret(secret bool) {
+	f.secret = secret
+	if secret {
+		f.input.EchoMode = textinput.EchoPassword
+	} else {
+		f.input.EchoMode = textinput.EchoNormal
+	}
+}
+
+func (p PrefilledProvider) valuesFor(kind string) map[string]string {
+	switch kind {
+	case fieldServer:
+		return p.server
+	case fieldPath:
+		return p.path
+	case fieldQuery:
+		return p.query
+	case fieldHeader:
+		return p.header
+	case fieldCookie:
+		return p.cookie
+	}
+	return nil
+}
+
 func (m paramFormModel) Init() tea.Cmd {
 	return nil
 }
@@ -201,12 +349,23 @@ func (m paramFormModel) View() string {
 		Background(lipgloss.Color("#2b2d3a")).
 		Padding(0, 1)
 	recordStatus := offStyle.Render("Recording OFF")
-	if m.recording {
+	if m.recording && m.editing {
+		recordStatus = onStyle.Render("Saving changes to preset")
+	} else if m.recording {
 		recordStatus = onStyle.Render("Recording ON")
 	}
+	if m.recording && m.local {
+		recordStatus += " " + offStyle.Render("Local (only you)")
+	} else if m.recording {
+		recordStatus += " " + offStyle.Render("Shared")
+	}
 
 	var sections []string
 	statusLines := []string{recordStatus}
+	if len(m.fields) > 0 && m.fields[0].kind == fieldPresetName {
+		label := lipgloss.NewStyle().Foreground(theme.Muted).Render(m.fields[0].label)
+		statusLines = append(statusLines, label+"\n"+m.fields[0].input.View())
+	}
 
 	sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, statusLines...))
 	sections = append(sections, "")
@@ -214,42 +373,90 @@ func (m paramFormModel) View() string {
 	hints := []string{
 		"Tab/Shift+Tab: move",
 		"Ctrl+r: toggle recording",
+		"Ctrl+l: shared/local",
+		"Ctrl+t: toggle secret",
 		"Enter: submit (newline in Body)",
 		"Ctrl+s: submit",
+		"Ctrl+f: send anyway",
+		"Ctrl+p: save preset without sending",
+		"Ctrl+n/Ctrl+x: add/remove header",
+		"Ctrl+g: generate body",
+		"Ctrl+o: body tree/raw",
+		"Ctrl+y: credentials",
+		"Ctrl+b: back",
 		"Esc: cancel",
 	}
 	sections = append(sections, lipgloss.NewStyle().Faint(true).Render(strings.Join(hints, "  ")))
+	if m.checked && m.problems > 0 {
+		notice := fmt.Sprintf("%d value(s) do not fit the spec: fix them, or press Ctrl+f to send anyway", m.problems)
+		sections = append(sections, errorStyle.Render(notice))
+	}
 	sections = append(sections, "")
 
-	if len(m.pathFields) > 0 {
-		var pathViews []string
-		for _, f := range m.pathFields {
-			label := lipgloss.NewStyle().Foreground(theme.Muted).Render(fmt.Sprintf("%s (%s)", f.p.Name, f.p.Schema.Type))
-			pathViews = append(pathViews, label+"\n"+f.input.View())
+	for _, sec := range fieldSections {
+		var views []string
+		for _, f := range m.fields {
+			if f.kind != sec.kind {
+				continue
+			}
+			label := lipgloss.NewStyle().Foreground(theme.Muted).Render(f.label + secretSuffix(f.secret))
+			view := label + "\n" + f.input.View()
+			if f.err != "" {
+				view += "\n" + errorStyle.Render(f.err)
+			}
+			views = append(views, view)
+		}
+		if len(views) == 0 {
+			continue
 		}
-		sections = append(sections, section.Render("Path Params"))
-		sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, pathViews...))
-	}
-
-	if len(m.queryFields) > 0 {
 		if len(sections) > 0 {
 			sections = append(sections, "")
 		}
-		var queryViews []string
-		for _, f := range m.queryFields {
-			label := lipgloss.NewStyle().Foreground(theme.Muted).Render(fmt.Sprintf("%s (%s)", f.p.Name, f.p.Schema.Type))
-			queryViews = append(queryViews, label+"\n"+f.input.View())
+		sections = append(sections, section.Render(sec.title))
+		sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, views...))
+	}
+
+	var headerRows []string
+	for i, f := range m.fields {
+		if f.kind == fieldCustomName && i+1 < len(m.fields) {
+			value := m.fields[i+1]
+			suffix := lipgloss.NewStyle().Foreground(theme.Muted).Render(secretSuffix(value.secret))
+			headerRows = append(headerRows, lipgloss.JoinHorizontal(lipgloss.Top, f.input.View(), value.input.View(), suffix))
 		}
-		sections = append(sections, section.Render("Query Params"))
-		sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, queryViews...))
 	}
+	if len(headerRows) == 0 {
+		headerRows = append(headerRows, lipgloss.NewStyle().Foreground(theme.Muted).Render("No custom headers (Ctrl+n to add)"))
+	}
+	if len(sections) > 0 {
+		sections = append(sections, "")
+	}
+	sections = append(sections, section.Render("Headers"))
+	sections = append(sections, lipgloss.JoinVertical(lipgloss.Left, headerRows...))
 
 	if m.hasBody {
 		if len(sections) > 0 {
 			sections = append(sections, "")
 		}
-		sections = append(sections, section.Render("Body (JSON)"))
-		sections = append(sections, m.bodyArea.View())
+		sections = append(sections, section.Render("Body (JSON)"+secretSuffix(m.bodySecret))+" "+m.skeletonLabel())
+		if m.treeMode {
+			sections = append(sections, m.bodyTree.View())
+		} else {
+			sections = append(sections, m.bodyArea.View())
+		}
+		if m.bodyNote != "" {
+			sections = append(sections, lipgloss.NewStyle().Foreground(theme.Muted).Render(m.bodyNote))
+		}
+		for i, e := range m.bodyErrs {
+			if i == maxBodyErrors {
+				sections = append(sections, errorStyle.Render(fmt.Sprintf("and %d more", len(m.bodyErrs)-i)))
+				break
+			}
+			msg := e.Message
+			if e.Name != "" {
+				msg = e.Name + ": " + msg
+			}
+			sections = append(sections, errorStyle.Render(msg))
+		}
 	}
 
 	if len(sections) > 0 {
@@ -262,6 +469,15 @@ func (m paramFormModel) View() string {
 }
 
 func (m paramFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if f, ok := next.(paramFormModel); ok && f.checked {
+		f.validate()
+		return f, cmd
+	}
+	return next, cmd
+}
+
+func (m paramFormModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -269,23 +485,60 @@ func (m paramFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.hasBody {
 			m.bodyArea.SetWidth(m.width - 8)
 			m.bodyArea.SetHeight(m.height / 3)
+			m.bodyTree.SetSize(m.width-8, m.height/3)
 		}
-		for i := range m.pathFields {
-			m.pathFields[i].input.Width = m.width - 8
-		}
-		for i := range m.queryFields {
-			m.queryFields[i].input.Width = m.width - 8
-		}
+		m.resizeFields()
 	case tea.KeyMsg:
+		if _, kind := m.currentIndex(); kind == "body" && m.treeMode {
+			switch msg.String() {
+			case "ctrl+n", "ctrl+x", "up", "down":
+				var (
+					cmd     tea.Cmd
+					handled bool
+				)
+				if m.bodyTree, cmd, handled = m.bodyTree.Update(msg); handled {
+					return m, cmd
+				}
+			}
+		}
 		switch msg.String() {
 		case "ctrl+s":
-			return m, tea.Quit
+			return m.trySubmit(false)
+		case "ctrl+f":
+			return m.trySubmit(true)
+		case "ctrl+p":
+			provider := m.toProvider()
+			provider.recording = true
+			return m, finish(formResult{provider: provider, save: true})
+		case "ctrl+b":
+			return m, finish(formResult{back: true})
+		case "ctrl+y":
+			return m, finish(formResult{credentials: true})
 		case "ctrl+r":
-			m.recording = !m.recording
+			m.toggleRecording()
+			return m, nil
+		case "ctrl+l":
+			if m.recording {
+				m.local = !m.local
+			}
+			return m, nil
+		case "ctrl+t":
+			m.toggleSecret()
+			return m, nil
+		case "ctrl+g":
+			m.regenerateBody()
+			return m, nil
+		case "ctrl+o":
+			m.toggleBodyMode()
+			return m, nil
+		case "ctrl+n":
+			m.addCustomHeader()
+			return m, nil
+		case "ctrl+x":
+			m.removeCustomHeader()
 			return m, nil
 		case "esc":
-			m.canceled = true
-			return m, tea.Quit
+			return m, finish(formResult{canceled: true})
 		case "tab":
 			m.focusNext()
 			m.applyFocus()
@@ -295,14 +548,14 @@ func (m paramFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.applyFocus()
 			return m, nil
 		case "enter":
-			if _, kind := m.currentIndex(); kind == "body" {
+			if _, kind := m.currentIndex(); kind == "body" && !m.treeMode {
 				var cmd tea.Cmd
 				m.bodyArea, cmd = m.bodyArea.Update(msg)
 				return m, cmd
 			}
-			return m, tea.Quit
+			return m.trySubmit(false)
 		case "up":
-			if _, kind := m.currentIndex(); kind == "body" {
+			if _, kind := m.currentIndex(); kind == "body" && !m.treeMode {
 				var cmd tea.Cmd
 				m.bodyArea, cmd = m.bodyArea.Update(msg)
 				return m, cmd
@@ -311,7 +564,7 @@ func (m paramFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.applyFocus()
 			return m, nil
 		case "down":
-			if _, kind := m.currentIndex(); kind == "body" {
+			if _, kind := m.currentIndex(); kind == "body" && !m.treeMode {
 				var cmd tea.Cmd
 				m.bodyArea, cmd = m.bodyArea.Update(msg)
 				return m, cmd
@@ -322,96 +575,373 @@ func (m paramFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	if idx, kind := m.currentIndex(); kind == "path" {
-		var cmd tea.Cmd
-		m.pathFields[idx].input, cmd = m.pathFields[idx].input.Update(msg)
-		return m, cmd
-	} else if kind == "query" {
+	if idx, kind := m.currentIndex(); kind == "field" {
 		var cmd tea.Cmd
-		m.queryFields[idx].input, cmd = m.queryFields[idx].input.Update(msg)
+		m.fields[idx].input, cmd = m.fields[idx].input.Update(msg)
 		return m, cmd
 	}
 	var cmd tea.Cmd
+	if m.treeMode {
+		m.bodyTree, cmd, _ = m.bodyTree.Update(msg)
+		return m, cmd
+	}
 	m.bodyArea, cmd = m.bodyArea.Update(msg)
 
 	return m, cmd
 }
 
-func (m *paramFormModel) currentIndex() (int, string) {
-	if m.focusedIndex < len(m.pathFields) {
-		return m.focusedIndex, "path"
+// trySubmit sends the values, or holds them back and shows what does not
+// fit the spec unless force is set.
+func (m paramFormModel) trySubmit(force bool) (tea.Model, tea.Cmd) {
+	m.validate()
+	if m.problems > 0 && !force {
+		m.checked = true
+		return m, nil
+	}
+	return m, m.submit()
+}
+
+// validate checks the values against the spec and places the problems on
+// their fields.
+func (m *paramFormModel) validate() {
+	var provider request.InputProvider = m.toProvider()
+	if m.inEnv != nil {
+		provider = m.inEnv(provider)
+	}
+	errs := request.Validate(m.server, m.ep, provider)
+	if m.treeMode {
+		var invalid []request.FieldError
+		for _, pointer := range slices.Sorted(maps.Keys(m.bodyTree.Invalid())) {
+			invalid = append(invalid, request.FieldError{Kind: "body", Name: pointer, Message: "is not a number"})
+		}
+		errs = append(invalid, errs...)
+	}
+	for i := range m.fields {
+		m.fields[i].err = ""
+	}
+	m.bodyErrs = m.bodyErrs[:0]
+	bodyErrs := map[string]string{}
+	for _, e := range errs {
+		if e.Kind == "body" {
+			m.bodyErrs = append(m.bodyErrs, e)
+			if _, ok := bodyErrs[e.Name]; !ok {
+				bodyErrs[e.Name] = e.Message
+			}
+			continue
+		}
+		for i, f := range m.fields {
+			if f.kind == e.Kind && f.name == e.Name && f.err == "" {
+				m.fields[i].err = e.Message
+			}
+		}
+	}
+	m.bodyTree.SetErrors(bodyErrs)
+	m.problems = len(errs)
+}
+
+func (m paramFormModel) submit() tea.Cmd {
+	return finish(formResult{provider: m.toProvider()})
+}
+
+// regenerateBody replaces the body with a skeleton from the schema. Each
+// press alternates between required and all properties, and moves to the
+// next oneOf/anyOf variant after all properties.
+func (m *paramFormModel) regenerateBody() {
+	body := m.ep.Operation.RequestBody
+	if !m.hasBody {
+		return
+	}
+	var next spec.SkeletonOptions
+	switch {
+	case !m.generated:
+	case !m.skeleton.Full:
+		next = m.skeleton
+		next.Full = true
+	default:
+		next = m.skeleton
+		next.Full = false
+		if mt, ok := body.JSONMediaType(); ok && mt.Schema.HasAlternatives() {
+			next.Variant++
+		}
+	}
+	skeleton := body.SkeletonBody(next)
+	if skeleton == "" {
+		return
+	}
+	m.skeleton, m.generated = next, true
+	m.setBody(skeleton)
+}
+
+// toggleBodyMode switches the body between the raw text and the tree,
+// carrying the value over. A body that is not valid JSON stays raw.
+func (m *paramFormModel) toggleBodyMode() {
+	if !m.hasBody {
+		return
+	}
+	m.bodyNote = ""
+	if m.treeMode {
+		if len(m.bodyTree.Invalid()) > 0 {
+			m.bodyNote = "Some numbers do not parse; fix them before switching to the raw JSON"
+			return
+		}
+		m.treeMode = false
+		m.bodyArea.SetValue(m.bodyTree.JSON())
+		m.applyFocus()
+		return
+	}
+	var schema *spec.Schema
+	if mt, ok := m.ep.Operation.RequestBody.JSONMediaType(); ok {
+		schema = mt.Schema
+	}
+	tree, err := newBodyTree(schema, m.bodyArea.Value())
+	if err != nil {
+		m.bodyNote = "The body is not valid JSON, so it stays raw: " + err.Error()
+		return
+	}
+	tree.SetSize(m.width-8, m.height/3)
+	m.bodyTree, m.treeMode = tree, true
+	m.applyFocus()
+}
+
+// bodyText is the body in either mode.
+func (m paramFormModel) bodyText() string {
+	if m.treeMode {
+		return m.bodyTree.JSON()
+	}
+	return m.bodyArea.Value()
+}
+
+// setBody replaces the body in either mode.
+func (m *paramFormModel) setBody(text string) {
+	m.bodyArea.SetValue(text)
+	if m.treeMode {
+		m.treeMode = false
+		m.toggleBodyMode()
 	}
-	j := m.focusedIndex - len(m.pathFields)
-	if j < len(m.queryFields) {
-		return j, "query"
+}
+
+// skeletonLabel describes the last generated body.
+func (m paramFormModel) skeletonLabel() string {
+	mt, ok := m.ep.Operation.RequestBody.JSONMediaType()
+	if !ok || mt.Schema == nil {
+		return ""
+	}
+	if !m.generated {
+		return lipgloss.NewStyle().Foreground(theme.Muted).Render("Ctrl+g: generate from schema")
+	}
+	label := "required fields"
+	if m.skeleton.Full {
+		label = "all fields"
+	}
+	if mt.Schema.HasAlternatives() {
+		label += fmt.Sprintf(", variant %d", m.skeleton.Variant+1)
+	}
+	return lipgloss.NewStyle().Foreground(theme.Muted).Render("Ctrl+g: " + label)
+}
+
+// saved is called once the submitted values were recorded as a preset.
+// Recording and editing stop so sending again does not save a second copy.
+func (m *paramFormModel) saved() {
+	m.editing = false
+	if m.recording {
+		m.toggleRecording()
+	}
+}
+
+// toggleRecording switches recording and shows the preset name field while
+// it is on. The name survives toggling off and on again.
+func (m *paramFormModel) toggleRecording() {
+	m.recording = !m.recording
+	if m.recording {
+		ti := textinput.New()
+		ti.Prompt = "> "
+		ti.Placeholder = "e.g. admin-user (empty: unnamed)"
+		ti.SetValue(m.presetName)
+		field := paramField{kind: fieldPresetName, label: "Preset name (same name replaces it)", input: ti}
+		m.fields = append([]paramField{field}, m.fields...)
+		m.focusedIndex = 0
+	} else if len(m.fields) > 0 && m.fields[0].kind == fieldPresetName {
+		m.presetName = strings.TrimSpace(m.fields[0].input.Value())
+		m.fields = m.fields[1:]
+		m.focusedIndex = max(m.focusedIndex-1, 0)
+	}
+	m.resizeFields()
+	m.applyFocus()
+}
+
+// toggleSecret marks the focused field as secret or not. Server variables
+// and the preset name are never saved as preset values.
+func (m *paramFormModel) toggleSecret() {
+	idx, kind := m.currentIndex()
+	switch kind {
+	case "body":
+		m.bodySecret = !m.bodySecret
+	case "field":
+		if m.fields[idx].kind == fieldCustomName {
+			idx++
+		}
+		switch m.fields[idx].kind {
+		case fieldServer, fieldPresetName:
+			return
+		}
+		m.fields[idx].setSecret(!m.fields[idx].secret)
+	}
+}
+
+func secretSuffix(secret bool) string {
+	if secret {
+		return " (secret)"
+	}
+	return ""
+}
+
+func (m *paramFormModel) resizeFields() {
+	for i := range m.fields {
+		switch m.fields[i].kind {
+		case fieldCustomName:
+			m.fields[i].input.Width = customNameWidth
+		case fieldCustomValue:
+			m.fields[i].input.Width = m.width - customNameWidth - 14
+		default:
+			m.fields[i].input.Width = m.width - 8
+		}
+	}
+}
+
+// addCustomHeader inserts an empty header row after the existing fields and
+// focuses its name input.
+func (m *paramFormModel) addCustomHeader() {
+	m.fields = append(m.fields, newCustomHeaderRow("", "")...)
+	m.resizeFields()
+	m.focusedIndex = len(m.fields) - 2
+	m.applyFocus()
+}
+
+// removeCustomHeader deletes the header row that holds the focus.
+func (m *paramFormModel) removeCustomHeader() {
+	idx, kind := m.currentIndex()
+	if kind != "field" {
+		return
+	}
+	switch m.fields[idx].kind {
+	case fieldCustomName:
+	case fieldCustomValue:
+		idx--
+	default:
+		return
+	}
+	m.fields = append(m.fields[:idx], m.fields[idx+2:]...)
+	if m.focusedIndex >= idx {
+		m.focusedIndex = max(idx-1, 0)
+	}
+	m.applyFocus()
+}
+
+func (m *paramFormModel) currentIndex() (int, string) {
+	if m.focusedIndex < len(m.fields) {
+		return m.focusedIndex, "field"
 	}
 	if m.hasBody {
 		return -1, "body"
 	}
-	if len(m.queryFields) > 0 {
-		return len(m.queryFields) - 1, "query"
-	}
-	if len(m.pathFields) > 0 {
-		return len(m.pathFields) - 1, "path"
+	if len(m.fields) > 0 {
+		return len(m.fields) - 1, "field"
 	}
 
 	return -1, "none"
 }
 
 func (m *paramFormModel) focusNext() {
-	total := len(m.pathFields) + len(m.queryFields)
+	total := len(m.fields)
 	if m.hasBody {
 		total++
 	}
+	if total == 0 {
+		return
+	}
 	m.focusedIndex = (m.focusedIndex + 1) % total
 }
 
 func (m *paramFormModel) focusPrev() {
-	total := len(m.pathFields) + len(m.queryFields)
+	total := len(m.fields)
 	if m.hasBody {
 		total++
 	}
+	if total == 0 {
+		return
+	}
 	m.focusedIndex = (m.focusedIndex - 1 + total) % total
 }
 
 func (m *paramFormModel) blurAll() {
-	for i := range m.pathFields {
-		m.pathFields[i].input.Blur()
-	}
-	for i := range m.queryFields {
-		m.queryFields[i].input.Blur()
+	for i := range m.fields {
+		m.fields[i].input.Blur()
 	}
 	if m.hasBody {
 		m.bodyArea.Blur()
+		m.bodyTree.Blur()
 	}
 }
 
 func (m *paramFormModel) applyFocus() {
 	m.blurAll()
-	if idx, kind := m.currentIndex(); kind == "path" {
-		m.pathFields[idx].input.Focus()
-	} else if kind == "query" {
-		m.queryFields[idx].input.Focus()
+	if idx, kind := m.currentIndex(); kind == "field" {
+		m.fields[idx].input.Focus()
+	} else if kind == "body" && m.treeMode {
+		m.bodyTree.Focus()
 	} else if kind == "body" && m.hasBody {
 		m.bodyArea.Focus()
 	}
 }
 
 func (m paramFormModel) toProvider() PrefilledProvider {
-	pathVals := map[string]string{}
-	queryVals := map[string]string{}
-	for _, f := range m.pathFields {
-		pathVals[f.p.Name] = f.input.Value()
+	values := map[string]map[string]string{
+		fieldServer: {},
+		fieldPath:   {},
+		fieldQuery:  {},
+		fieldHeader: {},
+		fieldCookie: {},
+	}
+	custom := map[string]string{}
+	secrets := []string{}
+	name := m.presetName
+	for i, f := range m.fields {
+		switch f.kind {
+		case fieldPresetName:
+			name = strings.TrimSpace(f.input.Value())
+		case fieldCustomName:
+			if name := strings.TrimSpace(f.input.Value()); name != "" && i+1 < len(m.fields) {
+				custom[name] = m.fields[i+1].input.Value()
+				if m.fields[i+1].secret {
+					secrets = append(secrets, params.SecretRef(secretKindCustom, name))
+				}
+			}
+		case fieldCustomValue:
+		default:
+			values[f.kind][f.name] = f.input.Value()
+			if f.secret && f.kind != fieldServer {
+				secrets = append(secrets, params.SecretRef(f.kind, f.name))
+			}
+		}
 	}
-	for _, f := range m.queryFields {
-		queryVals[f.p.Name] = f.input.Value()
+	if m.hasBody && m.bodySecret {
+		secrets = append(secrets, params.SecretBody)
 	}
+	sort.Strings(secrets)
 
 	return PrefilledProvider{
-		path:      pathVals,
-		query:     queryVals,
-		body:      m.bodyArea.Value(),
+		server:    values[fieldServer],
+		path:      values[fieldPath],
+		query:     values[fieldQuery],
+		header:    values[fieldHeader],
+		cookie:    values[fieldCookie],
+		custom:    custom,
+		body:      m.bodyText(),
+		name:      name,
 		recording: m.recording,
-		reselect:  false,
+		editID:    m.editID,
+		editing:   m.editing,
+		local:     m.local,
+		secrets:   secrets,
 	}
 }
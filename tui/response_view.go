@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atolix/clyst/output"
+	"github.com/atolix/clyst/request"
+	"github.com/atolix/clyst/theme"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// responseAction is what the user picked in the response viewer.
+type responseAction int
+
+const (
+	// responseQuit ends the session.
+	responseQuit responseAction = iota
+	// responseResend sends the same request again.
+	responseResend
+	// responseEdit reopens the form with the values just sent.
+	responseEdit
+	// responseEndpoints goes back to the endpoint list.
+	responseEndpoints
+	// responseCredentials changes the stored credentials.
+	responseCredentials
+)
+
+type responseModel struct {
+	view    viewport.Model
+	title   string
+	content string
+	canEdit bool
+	ready   bool
+}
+
+// newResponseModel shows the result of a request, or sendErr when sending
+// failed. canEdit offers going back to the form.
+func newResponseModel(result request.ResultInfo, sendErr error, canEdit bool) responseModel {
+	m := responseModel{canEdit: canEdit}
+	if sendErr != nil {
+		m.title = "Request failed"
+		m.content = lipgloss.NewStyle().Foreground(theme.Muted).Render("Error sending request: " + sendErr.Error())
+		return m
+	}
+	m.title = fmt.Sprintf("%s %s  %s  %s",
+		strings.ToUpper(result.Request.Method),
+		result.Request.URL,
+		result.Response.Status,
+		result.Response.Elapsed.Round(time.Millisecond))
+	if n := len(result.Response.Issues); n > 0 {
+		m.title += fmt.Sprintf("  ✗ %d spec mismatch(es)", n)
+	}
+	m.content = output.Render(result)
+	return m
+}
+
+func (m responseModel) Init() tea.Cmd { return nil }
+
+func (m responseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		height := max(msg.Height-4, 1)
+		if !m.ready {
+			m.view = viewport.New(msg.Width, height)
+			m.view.SetHorizontalStep(4)
+			m.view.SetContent(m.content)
+			m.ready = true
+		} else {
+			m.view.Width, m.view.Height = msg.Width, height
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc":
+			return m, finish(responseQuit)
+		case "r":
+			return m, finish(responseResend)
+		case "a":
+			return m, finish(responseCredentials)
+		case "e":
+			if m.canEdit {
+				return m, finish(responseEdit)
+			}
+			return m, nil
+		case "ctrl+b":
+			return m, finish(responseEndpoints)
+		}
+	}
+
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m responseModel) View() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(theme.Primary).Render(m.title)
+	if !m.ready {
+		return title
+	}
+
+	hints := []string{"↑/↓ PgUp/PgDn: scroll", "←/→: pan", "r: send again"}
+	if m.canEdit {
+		hints = append(hints, "e: edit values")
+	}
+	hints = append(hints, "a: credentials", "Ctrl+b: endpoints", "q/Esc: quit")
+	status := fmt.Sprintf("%3.f%%  ", m.view.ScrollPercent()*100)
+	footer := lipgloss.NewStyle().Faint(true).Render(status + strings.Join(hints, "  "))
+
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", m.view.View(), footer)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/atolix/clyst/spec"
@@ -19,37 +20,88 @@ type EndpointItem struct {
 	Method    string
 	Path      string
 	Operation spec.Operation
+	// Webhook marks an OpenAPI 3.1 webhook; Path then holds the webhook name.
+	Webhook bool
 }
 
-func (i EndpointItem) Title() string       { return fmt.Sprintf("%s %s", strings.ToUpper(i.Method), i.Path) }
+func (i EndpointItem) Title() string {
+	if i.Webhook {
+		return fmt.Sprintf("%s %s (webhook)", strings.ToUpper(i.Method), i.Path)
+	}
+	return fmt.Sprintf("%s %s", strings.ToUpper(i.Method), i.Path)
+}
 func (i EndpointItem) Description() string { return i.Operation.Summary }
 func (i EndpointItem) FilterValue() string { return i.Path }
 
-type endpointModel struct {
-	list             list.Model
-	selected         *EndpointItem
-	width            int
-	height           int
-	switchSpecSelect bool
+// Endpoints lists the operations and webhooks of a spec next to the
+// details of the highlighted one.
+type Endpoints struct {
+	list   list.Model
+	width  int
+	height int
 }
 
+// EndpointResult is sent when the user leaves the endpoint list.
 type EndpointResult struct {
 	Selected         *EndpointItem
 	SwitchSpecSelect bool
+	// ShowHistory asks for the request history screen.
+	ShowHistory bool
 }
 
-func newEndpointsModel(items []list.Item) endpointModel {
+func NewEndpoints(items []list.Item) Endpoints {
 	const defaultWidth = 50
 	l := list.New(items, NewStyleDelegate(), defaultWidth, 40)
-	l.Title = "Api Endpoints  (press 'Ctrl+b' to back to spec selection)"
 	l.SetShowStatusBar(false)
+	l.Title = "Api Endpoints  (Ctrl+b: spec selection, Ctrl+r: history)"
+	return Endpoints{list: l}
+}
+
+// EndpointItems lists the operations of doc by method and path, followed by
+// its webhooks.
+func EndpointItems(doc *spec.OpenApiSpec) []list.Item {
+	var endpoints []EndpointItem
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			endpoints = append(endpoints, EndpointItem{
+				Method:    method,
+				Path:      path,
+				Operation: op,
+			})
+		}
+	}
 
-	return endpointModel{list: l}
+	for name, methods := range doc.Webhooks {
+		for method, op := range methods {
+			endpoints = append(endpoints, EndpointItem{
+				Method:    method,
+				Path:      name,
+				Operation: op,
+				Webhook:   true,
+			})
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Webhook != endpoints[j].Webhook {
+			return !endpoints[i].Webhook
+		}
+		if endpoints[i].Method == endpoints[j].Method {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+
+	items := make([]list.Item, 0, len(endpoints))
+	for _, ep := range endpoints {
+		items = append(items, ep)
+	}
+	return items
 }
 
-func (m endpointModel) Init() tea.Cmd { return nil }
+func (m Endpoints) Init() tea.Cmd { return nil }
 
-func (m endpointModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m Endpoints) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -58,13 +110,19 @@ func (m endpointModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
+			if m.list.FilterState() == list.Filtering {
+				break
+			}
 			if i, ok := m.list.SelectedItem().(EndpointItem); ok {
-				m.selected = &i
-				return m, tea.Quit
+				if i.Webhook {
+					return m, m.list.NewStatusMessage("webhooks are sent by the API and cannot be requested")
+				}
+				return m, finish(EndpointResult{Selected: &i})
 			}
 		case "ctrl+b":
-			m.switchSpecSelect = true
-			return m, tea.Quit
+			return m, finish(EndpointResult{SwitchSpecSelect: true})
+		case "ctrl+r":
+			return m, finish(EndpointResult{ShowHistory: true})
 		}
 	}
 
@@ -74,7 +132,7 @@ func (m endpointModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m endpointModel) View() string {
+func (m Endpoints) View() string {
 	listWidth := m.width / 2
 	if listWidth <= 0 {
 		listWidth = m.width
@@ -88,11 +146,11 @@ func (m endpointModel) View() string {
 	return lipgloss.JoinHorizontal(lipgloss.Top, listView(m, listWidth, height), detailBox(m, detailWidth, height))
 }
 
-func listView(m endpointModel, width int, height int) string {
+func listView(m Endpoints, width int, height int) string {
 	return lipgloss.NewStyle().Width(width).Height(height).Render(m.list.View())
 }
 
-func detailBox(m endpointModel, width int, height int) string {
+func detailBox(m Endpoints, width int, height int) string {
 	var detail string
 	if i, ok := m.list.SelectedItem().(EndpointItem); ok {
 		parsed, err := json.MarshalIndent(i.Operation, "", "  ")
@@ -115,15 +173,3 @@ func detailBox(m endpointModel, width int, height int) string {
 		BorderForeground(theme.Border).
 		Render(detail)
 }
-
-func RunEndpoints(items []list.Item) (EndpointResult, error) {
-	m := newEndpointsModel(items)
-	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
-	if err != nil {
-		return EndpointResult{}, err
-	}
-
-	fm := final.(endpointModel)
-
-	return EndpointResult{Selected: fm.selected, SwitchSpecSelect: fm.switchSpecSelect}, nil
-}
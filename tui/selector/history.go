@@ -0,0 +1,385 @@
+package selector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atolix/clyst/history"
+	"github.com/atolix/clyst/output"
+	"github.com/atolix/clyst/params"
+	"github.com/atolix/clyst/theme"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type historyItem struct {
+	index int
+	entry history.Entry
+	// marked is the entry picked as the left side of a diff.
+	marked bool
+}
+
+func (i historyItem) Title() string {
+	mark := ""
+	if i.marked {
+		mark = "● "
+	}
+	return fmt.Sprintf("%s%d %s %s", mark, i.entry.Response.StatusCode, strings.ToUpper(i.entry.Method), i.entry.Request.URL)
+}
+
+func (i historyItem) Description() string {
+	parts := []string{i.entry.Time.Local().Format(time.DateTime), i.entry.Response.Elapsed.Round(time.Millisecond).String()}
+	if i.entry.Environment != "" {
+		parts = append(parts, "["+i.entry.Environment+"]")
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (i historyItem) FilterValue() string {
+	return strings.ToUpper(i.entry.Method) + " " + i.entry.Request.URL
+}
+
+// History lists the request history of a spec.
+type History struct {
+	list    list.Model
+	specID  string
+	entries []history.Entry
+	marked  int
+	// viewing is set while an entry or a diff is shown in the viewport.
+	viewing bool
+	view    viewport.Model
+	// naming is set while the name input for promoting an entry is shown.
+	naming    bool
+	nameInput textinput.Model
+}
+
+// HistoryResult is sent when the user leaves the history. Resend is the
+// entry to send again, if any.
+type HistoryResult struct {
+	Resend   *history.Entry
+	Reselect bool
+	Canceled bool
+}
+
+// NewHistory lists entries, which belong to the spec with specID. Entries
+// can be inspected, diffed, saved as presets of the spec, or picked to be
+// sent again.
+func NewHistory(specID string, entries []history.Entry) History {
+	const defaultWidth = 60
+	l := list.New(nil, NewStyleDelegate(), defaultWidth, 20)
+	l.Title = "Request history (Esc: cancel, Ctrl+b: back)"
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+
+	ti := textinput.New()
+	ti.Prompt = "Preset name: "
+	ti.Placeholder = "empty for an unnamed preset"
+
+	m := History{list: l, specID: specID, entries: entries, marked: -1, nameInput: ti, view: viewport.New(defaultWidth, 20)}
+	m.reload()
+	return m
+}
+
+func (m *History) reload() {
+	items := make([]list.Item, 0, len(m.entries))
+	for idx, e := range m.entries {
+		items = append(items, historyItem{index: idx, entry: e, marked: idx == m.marked})
+	}
+	m.list.SetItems(items)
+}
+
+func (m History) current() (historyItem, bool) {
+	item, ok := m.list.SelectedItem().(historyItem)
+	return item, ok
+}
+
+func (m History) Init() tea.Cmd { return nil }
+
+func (m History) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.list.SetSize(size.Width-6, size.Height-8)
+		m.view.Width, m.view.Height = size.Width-6, size.Height-6
+		return m, nil
+	}
+	if m.naming {
+		return m.updateName(msg)
+	}
+	if m.viewing {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			switch key.String() {
+			case "esc", "q", "ctrl+b":
+				m.viewing = false
+				return m, nil
+			}
+		}
+		var cmd tea.Cmd
+		m.view, cmd = m.view.Update(msg)
+		return m, cmd
+	}
+
+	if key, ok := msg.(tea.KeyMsg); ok && m.list.FilterState() != list.Filtering {
+		switch key.String() {
+		case "ctrl+b":
+			return m, finish(HistoryResult{Reselect: true})
+		case "esc":
+			if m.list.FilterState() == list.FilterApplied {
+				break
+			}
+			return m, finish(HistoryResult{Canceled: true})
+		case "enter":
+			if item, ok := m.current(); ok {
+				m.show(output.Render(item.entry.Result()))
+			}
+			return m, nil
+		case "s":
+			if item, ok := m.current(); ok {
+				entry := item.entry
+				return m, finish(HistoryResult{Resend: &entry})
+			}
+			return m, nil
+		case "m":
+			if item, ok := m.current(); ok {
+				if m.marked == item.index {
+					m.marked = -1
+				} else {
+					m.marked = item.index
+				}
+				m.reload()
+			}
+			return m, nil
+		case "d":
+			item, ok := m.current()
+			if !ok {
+				return m, nil
+			}
+			if m.marked < 0 || m.marked == item.index {
+				return m, m.list.NewStatusMessage("mark an entry with m first, then press d on another")
+			}
+			m.show(renderDiff(m.entries[m.marked], item.entry))
+			return m, nil
+		case "p":
+			if _, ok := m.current(); ok {
+				m.naming = true
+				m.nameInput.SetValue("")
+				return m, m.nameInput.Focus()
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m *History) show(content string) {
+	m.view.SetContent(content)
+	m.view.GotoTop()
+	m.viewing = true
+}
+
+func (m History) updateName(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "esc":
+			m.naming = false
+			m.nameInput.Blur()
+			return m, nil
+		case "enter":
+			m.naming = false
+			m.nameInput.Blur()
+			item, ok := m.current()
+			if !ok {
+				return m, nil
+			}
+			if err := promote(m.specID, item.entry, m.nameInput.Value()); err != nil {
+				return m, m.list.NewStatusMessage("error: " + err.Error())
+			}
+			if len(item.entry.Input.Secrets) > 0 {
+				return m, m.list.NewStatusMessage("saved as preset; its secret values are not in the history, enter them when you use it")
+			}
+			return m, m.list.NewStatusMessage("saved as preset")
+		}
+	}
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
+// promote saves the values of e as a preset of its endpoint.
+func promote(specID string, e history.Entry, name string) error {
+	store, err := params.Load(".", specID)
+	if err != nil {
+		return err
+	}
+	preset := e.Input
+	preset.Name = strings.TrimSpace(name)
+	return store.AppendPreset(e.Method, e.Path, preset)
+}
+
+func (m History) View() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Border).
+		Padding(1, 2)
+	faint := lipgloss.NewStyle().Faint(true)
+
+	if m.viewing {
+		hints := faint.Render("↑/↓ PgUp/PgDn: scroll  Esc: back to the list")
+		return box.Render(lipgloss.JoinVertical(lipgloss.Left, m.view.View(), hints))
+	}
+
+	hints := faint.Render(strings.Join([]string{
+		"Enter: inspect",
+		"s: send again",
+		"m: mark",
+		"d: diff with marked",
+		"p: save as preset",
+		"/: filter",
+	}, "  "))
+	content := []string{m.list.View(), hints}
+	if len(m.entries) == 0 {
+		content = append(content, "", "No requests sent for this spec yet.")
+	}
+	if m.naming {
+		content = append(content, "", m.nameInput.View())
+	}
+	return box.Render(lipgloss.JoinVertical(lipgloss.Left, content...))
+}
+
+// renderDiff compares two entries line by line, from a to b.
+func renderDiff(a, b history.Entry) string {
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b"))
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color("#69db7c"))
+	title := lipgloss.NewStyle().Bold(true).Foreground(theme.Primary)
+
+	out := []string{
+		title.Render("- " + a.Time.Local().Format(time.DateTime)),
+		title.Render("+ " + b.Time.Local().Format(time.DateTime)),
+		"",
+	}
+	for _, l := range diffLines(describeEntry(a), describeEntry(b)) {
+		switch l[0] {
+		case '-':
+			out = append(out, removed.Render(l))
+		case '+':
+			out = append(out, added.Render(l))
+		default:
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// describeEntry lays an entry out as text for diffing.
+func describeEntry(e history.Entry) []string {
+	lines := []string{strings.ToUpper(e.Request.Method) + " " + e.Request.URL}
+	lines = append(lines, headerText(e.Request.Headers)...)
+	lines = append(lines, bodyText(e.Request.Body)...)
+	lines = append(lines, "", e.Response.Status)
+	lines = append(lines, headerText(e.Response.Headers)...)
+	lines = append(lines, bodyText(e.Response.Body)...)
+	return lines
+}
+
+func headerText(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		for _, v := range h[name] {
+			lines = append(lines, name+": "+v)
+		}
+	}
+	return lines
+}
+
+func bodyText(b history.Body) []string {
+	raw := b.Bytes()
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+	if b.Base64 != nil {
+		return []string{"", fmt.Sprintf("(%d bytes of binary data)", len(raw))}
+	}
+	var buf bytes.Buffer
+	if json.Indent(&buf, raw, "", "  ") == nil {
+		raw = buf.Bytes()
+	}
+	lines := append([]string{""}, strings.Split(strings.TrimRight(string(raw), "\n"), "\n")...)
+	if b.Truncated {
+		lines = append(lines, "(truncated)")
+	}
+	return lines
+}
+
+// maxDiffCells bounds the LCS table; larger inputs are shown as a whole
+// removal followed by a whole addition.
+const maxDiffCells = 4 << 20
+
+// diffLines returns a unified line diff: unchanged lines start with a
+// space, removed ones with "-" and added ones with "+".
+func diffLines(a, b []string) []string {
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		out := make([]string, 0, len(a)+len(b))
+		for _, l := range a {
+			out = append(out, "- "+l)
+		}
+		for _, l := range b {
+			out = append(out, "+ "+l)
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
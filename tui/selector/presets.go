@@ -11,6 +11,7 @@ import (
 	"github.com/atolix/clyst/theme"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -25,56 +26,153 @@ func (i presetItem) Title() string       { return i.title }
 func (i presetItem) Description() string { return i.desc }
 func (i presetItem) FilterValue() string { return i.title }
 
-type presetModel struct {
-	list     list.Model
-	selected int
-	canceled bool
-	reselect bool
+// Presets lists the saved presets of an endpoint and manages them.
+type Presets struct {
+	list    list.Model
+	ep      request.Endpoint
+	store   *params.Store
+	presets []params.StoredParams
+	// renaming is set while the name input is shown for the highlighted preset.
+	renaming      bool
+	renameInput   textinput.Model
+	confirmDelete bool
 }
 
-func newPresetModel(ep request.Endpoint, presets []params.StoredParams) presetModel {
-	items := make([]list.Item, 0, len(presets)+1)
+// PresetSelection is sent when the user leaves the preset list. Preset is
+// nil when the user chose to start from empty values. Edit asks the form to
+// save the values back to the preset with ID instead of recording a new
+// one. Reselect asks for the endpoint list again.
+type PresetSelection struct {
+	Preset   *params.StoredParams
+	ID       string
+	Edit     bool
+	Reselect bool
+	Canceled bool
+}
+
+// NewPresets lists the presets of ep. Changes such as renames and deletes
+// are written to store right away.
+func NewPresets(ep request.Endpoint, store *params.Store) Presets {
+	const defaultWidth = 60
+	l := list.New(nil, NewStyleDelegate(), defaultWidth, 20)
+	l.Title = fmt.Sprintf("Saved presets: %s %s (Esc: cancel, Ctrl+b: back)", strings.ToUpper(ep.Method), ep.Path)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowHelp(false)
+
+	ti := textinput.New()
+	ti.Prompt = "Name: "
+	ti.Placeholder = "empty to remove the name"
+
+	m := Presets{list: l, ep: ep, store: store, renameInput: ti}
+	m.reload()
+	return m
+}
+
+// reload rebuilds the items from the store after a change.
+func (m *Presets) reload() {
+	m.presets = m.store.PresetsFor(m.ep.Method, m.ep.Path)
+	items := make([]list.Item, 0, len(m.presets)+1)
 	items = append(items, presetItem{
 		index: 0,
 		title: "New values",
 		desc:  "Open form with empty fields",
 	})
 
-	for idx, preset := range presets {
+	for idx, preset := range m.presets {
 		items = append(items, presetItem{
 			index: idx + 1,
 			title: presetTitle(preset),
 			desc:  presetSummary(preset),
 		})
 	}
+	m.list.SetItems(items)
+}
 
-	const defaultWidth = 60
-	l := list.New(items, NewStyleDelegate(), defaultWidth, 20)
-	l.Title = fmt.Sprintf("Saved presets: %s %s (Esc: cancel, Ctrl+b: back)", strings.ToUpper(ep.Method), ep.Path)
-	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
-	l.SetShowHelp(false)
+func (m Presets) Init() tea.Cmd { return nil }
+
+// current returns the store index of the highlighted preset, or -1 on
+// "New values".
+func (m Presets) current() int {
+	if item, ok := m.list.SelectedItem().(presetItem); ok {
+		return item.index - 1
+	}
+	return -1
+}
 
-	return presetModel{list: l, selected: 0}
+// selection describes the highlighted item.
+func (m Presets) selection(edit bool) PresetSelection {
+	idx := m.current()
+	if idx < 0 {
+		return PresetSelection{}
+	}
+	preset := m.presets[idx]
+	return PresetSelection{Preset: &preset, ID: preset.ID, Edit: edit}
 }
 
-func (m presetModel) Init() tea.Cmd { return nil }
+func (m Presets) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.renaming {
+		return m.updateRename(msg)
+	}
 
-func (m presetModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width-6, msg.Height-8)
+		return m, nil
 	case tea.KeyMsg:
-		switch msg.String() {
+		key := msg.String()
+		if key != "d" {
+			m.confirmDelete = false
+		}
+		switch key {
 		case "ctrl+b":
-			m.reselect = true
-			return m, tea.Quit
+			return m, finish(PresetSelection{Reselect: true})
 		case "enter":
-			if item, ok := m.list.SelectedItem().(presetItem); ok {
-				m.selected = item.index
-				return m, tea.Quit
+			if _, ok := m.list.SelectedItem().(presetItem); ok {
+				return m, finish(m.selection(false))
 			}
 		case "esc":
-			m.canceled = true
-			return m, tea.Quit
+			return m, finish(PresetSelection{Canceled: true})
+		case "e":
+			if idx := m.current(); idx >= 0 {
+				return m, finish(m.selection(true))
+			}
+			return m, nil
+		case "d":
+			idx := m.current()
+			if idx < 0 {
+				return m, nil
+			}
+			if !m.confirmDelete {
+				m.confirmDelete = true
+				return m, m.list.NewStatusMessage("press d again to delete this preset")
+			}
+			m.confirmDelete = false
+			return m, m.apply(m.store.DeletePreset(m.ep.Method, m.ep.Path, idx), idx+1, "deleted")
+		case "r":
+			if idx := m.current(); idx >= 0 {
+				m.renaming = true
+				m.renameInput.SetValue(m.presets[idx].Name)
+				m.renameInput.CursorEnd()
+				return m, m.renameInput.Focus()
+			}
+			return m, nil
+		case "c":
+			if idx := m.current(); idx >= 0 {
+				return m, m.apply(m.store.DuplicatePreset(m.ep.Method, m.ep.Path, idx), idx+2, "duplicated")
+			}
+			return m, nil
+		case "K", "shift+up", "J", "shift+down":
+			idx := m.current()
+			if idx < 0 {
+				return m, nil
+			}
+			delta := 1
+			if key == "K" || key == "shift+up" {
+				delta = -1
+			}
+			to, err := m.store.MovePreset(m.ep.Method, m.ep.Path, idx, delta)
+			return m, m.apply(err, to+1, "")
 		}
 	}
 
@@ -83,31 +181,101 @@ func (m presetModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m presetModel) View() string {
+func (m Presets) updateRename(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "esc":
+			m.renaming = false
+			m.renameInput.Blur()
+			return m, nil
+		case "enter":
+			m.renaming = false
+			m.renameInput.Blur()
+			idx := m.current()
+			return m, m.apply(m.store.RenamePreset(m.ep.Method, m.ep.Path, idx, m.renameInput.Value()), idx+1, "renamed")
+		}
+	}
+	var cmd tea.Cmd
+	m.renameInput, cmd = m.renameInput.Update(msg)
+	return m, cmd
+}
+
+// apply reloads the list after a store change, reports the result and
+// puts the cursor on item.
+func (m *Presets) apply(err error, item int, done string) tea.Cmd {
+	// Reload either way: a failed change may be due to edits made by
+	// another instance, which the store has just read.
+	m.reload()
+	if err != nil {
+		return m.list.NewStatusMessage("error: " + err.Error())
+	}
+	m.list.Select(min(item, len(m.list.Items())-1))
+	if done == "" {
+		return nil
+	}
+	return m.list.NewStatusMessage("preset " + done)
+}
+
+func (m Presets) View() string {
 	box := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(theme.Border).
 		Padding(1, 2)
 
-	return box.Render(m.list.View())
+	hints := lipgloss.NewStyle().Faint(true).Render(strings.Join([]string{
+		"Enter: use",
+		"e: edit",
+		"r: rename",
+		"c: duplicate",
+		"d: delete",
+		"Shift+↑/↓ (K/J): move",
+	}, "  "))
+	content := []string{m.list.View(), hints}
+	if m.renaming {
+		content = append(content, "", m.renameInput.View())
+	}
+
+	return box.Render(lipgloss.JoinVertical(lipgloss.Left, content...))
 }
 
 func presetTitle(p params.StoredParams) string {
-	if p.RecordedAt.IsZero() {
-		return "Saved preset"
+	var title string
+	switch {
+	case p.Name != "" && !p.RecordedAt.IsZero():
+		title = p.Name + "  " + p.RecordedAt.Local().Format(time.DateTime)
+	case p.Name != "":
+		title = p.Name
+	case p.RecordedAt.IsZero():
+		title = "Saved preset"
+	default:
+		title = p.RecordedAt.Local().Format(time.DateTime)
 	}
-	return p.RecordedAt.Local().Format(time.DateTime)
+	if p.Local {
+		title += "  (local)"
+	}
+	return title
 }
 
 func presetSummary(p params.StoredParams) string {
 	var parts []string
 	if len(p.Path) > 0 {
-		parts = append(parts, "Path "+joinPairs(p.Path))
+		parts = append(parts, "Path "+joinPairs(p, "path", p.Path))
 	}
 	if len(p.Query) > 0 {
-		parts = append(parts, "Query "+joinPairs(p.Query))
+		parts = append(parts, "Query "+joinPairs(p, "query", p.Query))
+	}
+	if len(p.Header) > 0 {
+		parts = append(parts, "Header "+joinPairs(p, "header", p.Header))
+	}
+	if len(p.Cookie) > 0 {
+		parts = append(parts, "Cookie "+joinPairs(p, "cookie", p.Cookie))
+	}
+	if len(p.CustomHeaders) > 0 {
+		parts = append(parts, "Headers "+joinPairs(p, "custom_headers", p.CustomHeaders))
 	}
-	if strings.TrimSpace(p.Body) != "" {
+	if p.IsSecret(params.SecretBody) {
+		parts = append(parts, "Body (secret)")
+	} else if strings.TrimSpace(p.Body) != "" {
 		parts = append(parts, fmt.Sprintf("Body %d chars", len(p.Body)))
 	} else {
 		parts = append(parts, "Body empty")
@@ -115,7 +283,8 @@ func presetSummary(p params.StoredParams) string {
 	return strings.Join(parts, "  ")
 }
 
-func joinPairs(m map[string]string) string {
+// joinPairs lists the values of one field kind, masking secret ones.
+func joinPairs(p params.StoredParams, kind string, m map[string]string) string {
 	if len(m) == 0 {
 		return ""
 	}
@@ -127,30 +296,11 @@ func joinPairs(m map[string]string) string {
 
 	out := make([]string, 0, len(keys))
 	for _, k := range keys {
-		out = append(out, fmt.Sprintf("%s=%s", k, m[k]))
+		v := m[k]
+		if p.IsSecret(params.SecretRef(kind, k)) {
+			v = "•••"
+		}
+		out = append(out, fmt.Sprintf("%s=%s", k, v))
 	}
 	return strings.Join(out, ", ")
 }
-
-func SelectPreset(ep request.Endpoint, presets []params.StoredParams) (*params.StoredParams, bool, bool, error) {
-	if len(presets) == 0 {
-		return nil, false, false, nil
-	}
-
-	model := newPresetModel(ep, presets)
-	final, err := tea.NewProgram(model, tea.WithAltScreen()).Run()
-	if err != nil {
-		return nil, false, false, err
-	}
-	res := final.(presetModel)
-	if res.canceled {
-		return nil, false, true, nil
-	}
-	if res.reselect {
-		return nil, true, false, nil
-	}
-	if res.selected == 0 {
-		return nil, false, false, nil
-	}
-	return &presets[res.selected-1], false, false, nil
-}
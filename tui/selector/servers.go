@@ -0,0 +1,91 @@
+package selector
+
+import (
+	"fmt"
+
+	"github.com/atolix/clyst/spec"
+	"github.com/atolix/clyst/theme"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type serverItem struct {
+	server spec.Server
+}
+
+func (i serverItem) Title() string { return i.server.URL }
+func (i serverItem) Description() string {
+	if i.server.Description != "" {
+		return i.server.Description
+	}
+	if n := len(i.server.Variables); n > 0 {
+		return fmt.Sprintf("%d variable(s)", n)
+	}
+	return ""
+}
+func (i serverItem) FilterValue() string { return i.server.URL }
+
+// Servers lets the user pick one of the servers that apply to an endpoint.
+type Servers struct {
+	list list.Model
+}
+
+// ServerResult is sent when the user leaves the server list. Reselect
+// asks for the endpoint list again.
+type ServerResult struct {
+	Server   *spec.Server
+	Reselect bool
+	Canceled bool
+}
+
+func NewServers(servers []spec.Server) Servers {
+	items := make([]list.Item, 0, len(servers))
+	for _, s := range servers {
+		items = append(items, serverItem{server: s})
+	}
+
+	const defaultWidth = 60
+	l := list.New(items, NewStyleDelegate(), defaultWidth, 20)
+	l.Title = "Select a server (Esc: cancel, Ctrl+b: back)"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowHelp(false)
+
+	return Servers{list: l}
+}
+
+func (m Servers) Init() tea.Cmd { return nil }
+
+func (m Servers) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width-6, msg.Height-4)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+b":
+			return m, finish(ServerResult{Reselect: true})
+		case "enter":
+			if item, ok := m.list.SelectedItem().(serverItem); ok {
+				server := item.server
+				return m, finish(ServerResult{Server: &server})
+			}
+		case "esc":
+			return m, finish(ServerResult{Canceled: true})
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Servers) View() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Border).
+		Padding(1, 2)
+
+	return box.Render(m.list.View())
+}
@@ -1,8 +1,6 @@
 package selector
 
 import (
-	"fmt"
-
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -17,31 +15,46 @@ func (i SpecItem) Title() string       { return i.TitleText }
 func (i SpecItem) Description() string { return i.DescText }
 func (i SpecItem) FilterValue() string { return i.TitleText }
 
-type specModel struct {
-	list     list.Model
-	selected *SpecItem
+// SpecResult is sent when a spec was picked.
+type SpecResult struct {
+	Value string
+}
+
+// Specs lists the spec files found.
+type Specs struct {
+	list list.Model
 }
 
-func newSpecModel(title string, items []list.Item) specModel {
+func NewSpecs(title string, options []SpecItem) Specs {
+	items := make([]list.Item, 0, len(options))
+	for _, o := range options {
+		items = append(items, o)
+	}
+
 	const defaultWidth = 120
 	l := list.New(items, NewStyleDelegate(), defaultWidth, 20)
 	l.Title = title
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 
-	return specModel{list: l}
+	return Specs{list: l}
 }
 
-func (m specModel) Init() tea.Cmd { return nil }
+func (m Specs) Init() tea.Cmd { return nil }
 
-func (m specModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m Specs) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		return m, nil
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
+			if m.list.FilterState() == list.Filtering {
+				break
+			}
 			if i, ok := m.list.SelectedItem().(SpecItem); ok {
-				m.selected = &i
-				return m, tea.Quit
+				return m, finish(SpecResult{Value: i.Value})
 			}
 		}
 	}
@@ -51,28 +64,4 @@ func (m specModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m specModel) View() string { return m.list.View() }
-
-func SelectSpec(title string, options []SpecItem) (string, error) {
-	if len(options) == 0 {
-		return "", fmt.Errorf("no options provided")
-	}
-
-	items := make([]list.Item, 0, len(options))
-	for _, o := range options {
-		items = append(items, o)
-	}
-
-	m := newSpecModel(title, items)
-	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
-	if err != nil {
-		return "", err
-	}
-
-	fm := final.(specModel)
-	if fm.selected == nil {
-		return "", nil
-	}
-
-	return fm.selected.Value, nil
-}
+func (m Specs) View() string { return m.list.View() }
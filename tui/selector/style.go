@@ -4,6 +4,7 @@ import (
 	"github.com/atolix/clyst/theme"
 
 	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -32,3 +33,9 @@ func NewStyleDelegate() list.DefaultDelegate {
 
 	return d
 }
+
+// finish reports a screen's result to the host model, which decides where
+// to go next.
+func finish(result tea.Msg) tea.Cmd {
+	return func() tea.Msg { return result }
+}